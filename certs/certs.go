@@ -0,0 +1,208 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"time"
+)
+
+type (
+	// Authority is a self-signed certificate authority that issues the certificates gnockgnock serves and, for mutual
+	// TLS, its clients present
+	Authority struct {
+		cert *x509.Certificate
+		key  *ecdsa.PrivateKey
+		pem  []byte
+	}
+
+	// Settings describe how the mocks are served over TLS
+	Settings struct {
+		// CertFile and KeyFile are a certificate and key to serve
+		CertFile string
+		KeyFile  string
+		// Generate creates an Authority and serves a certificate it issues for Hosts when no CertFile is given
+		Generate bool
+		Hosts    []string
+		// ClientCAFile holds the PEM certificates client certificates are verified against, a generated Authority is
+		// trusted as well
+		ClientCAFile string
+		// ClientAuth is none, request, require, verifyIfGiven or verify
+		ClientAuth string
+	}
+)
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":              tls.NoClientCert,
+	"none":          tls.NoClientCert,
+	"request":       tls.RequestClientCert,
+	"require":       tls.RequireAnyClientCert,
+	"verifyIfGiven": tls.VerifyClientCertIfGiven,
+	"verify":        tls.RequireAndVerifyClientCert,
+}
+
+// Setup builds the TLS config the settings describe, nil when TLS isn't configured.  The Authority is only returned
+// when one was generated.
+func Setup(settings Settings) (*tls.Config, *Authority, error) {
+	if settings.CertFile == "" && !settings.Generate {
+		return nil, nil, nil
+	}
+
+	clientAuth, ok := clientAuthTypes[settings.ClientAuth]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown client auth %s", settings.ClientAuth)
+	}
+
+	config := &tls.Config{ClientAuth: clientAuth, MinVersion: tls.VersionTLS12}
+	clientCAs := x509.NewCertPool()
+
+	var authority *Authority
+	if settings.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(settings.CertFile, settings.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	} else {
+		var err error
+		if authority, err = NewAuthority(); err != nil {
+			return nil, nil, err
+		}
+
+		cert, err := authority.Issue(settings.Hosts...)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+		clientCAs.AddCert(authority.cert)
+	}
+
+	if settings.ClientCAFile != "" {
+		caPEM, err := ioutil.ReadFile(settings.ClientCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read client CAs: %w", err)
+		}
+
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			return nil, nil, errors.New("no certificates found in " + settings.ClientCAFile)
+		}
+	}
+
+	config.ClientCAs = clientCAs
+
+	return config, authority, nil
+}
+
+// NewAuthority generates a new certificate authority
+func NewAuthority() (*Authority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := certificateTemplate("GnockGnock CA")
+	if err != nil {
+		return nil, err
+	}
+
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Authority{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}, nil
+}
+
+// PEM is the authority's certificate, for clients to trust
+func (a *Authority) PEM() []byte {
+	return a.pem
+}
+
+// Issue creates a server certificate for the hosts, which may be names or IPs
+func (a *Authority) Issue(hosts ...string) (tls.Certificate, error) {
+	_, _, cert, err := a.issue("GnockGnock", x509.ExtKeyUsageServerAuth, hosts)
+	return cert, err
+}
+
+// IssueClient creates a client certificate, returning it and its key PEM encoded
+func (a *Authority) IssueClient(commonName string) (certPEM, keyPEM []byte, err error) {
+	certPEM, keyPEM, _, err = a.issue(commonName, x509.ExtKeyUsageClientAuth, nil)
+	return certPEM, keyPEM, err
+}
+
+func (a *Authority) issue(commonName string, usage x509.ExtKeyUsage, hosts []string) ([]byte, []byte, tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, tls.Certificate{}, err
+	}
+
+	template, err := certificateTemplate(commonName)
+	if err != nil {
+		return nil, nil, tls.Certificate{}, err
+	}
+
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		return nil, nil, tls.Certificate{}, fmt.Errorf("failed to issue certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, tls.Certificate{}, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+
+	return certPEM, keyPEM, cert, err
+}
+
+func certificateTemplate(commonName string) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"GnockGnock"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(365 * 24 * time.Hour),
+	}, nil
+}
@@ -1,18 +1,31 @@
 package config
 
 import (
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
 )
 
 type (
 	// Env holds the values of environment variable based configuration
 	Env struct {
-		Host           string `envconfig:"HOST" default:"127.0.0.1"`
-		Port           int    `envconfig:"PORT" default:"8080"`
-		ConfigPort     int    `envconfig:"CONFIG_PORT" default:"8081"`
-		ConfigFilePath string `envconfig:"GNOCK_CONFIG" default:"./gnockgnock.yaml"`
-		ConfigBasePath string `envconfig:"GNOCK_BASE_PATH" default:"/gnockconfig"`
-		LogLevel       string `envconfig:"LOG_LEVEL" default:"debug"`
+		Host               string        `envconfig:"HOST" default:"127.0.0.1"`
+		Port               int           `envconfig:"PORT" default:"8080"`
+		ConfigPort         int           `envconfig:"CONFIG_PORT" default:"8081"`
+		SinglePort         bool          `envconfig:"SINGLE_PORT" default:"false"`
+		ConfigFilePath     string        `envconfig:"GNOCK_CONFIG" default:"./gnockgnock.yaml"`
+		ConfigPollInterval time.Duration `envconfig:"GNOCK_CONFIG_POLL" default:"5s"`
+		ConfigBasePath     string        `envconfig:"GNOCK_BASE_PATH" default:"/gnockconfig"`
+		StrictConfig       bool          `envconfig:"GNOCK_STRICT" default:"true"`
+		LogLevel           string        `envconfig:"LOG_LEVEL" default:"debug"`
+		JournalSize        int           `envconfig:"JOURNAL_SIZE" default:"1000"`
+		ProxyURL           string        `envconfig:"PROXY_URL"`
+		TLSCertFile        string        `envconfig:"TLS_CERT_FILE"`
+		TLSKeyFile         string        `envconfig:"TLS_KEY_FILE"`
+		TLSGenerate        bool          `envconfig:"TLS_GENERATE" default:"false"`
+		TLSHosts           []string      `envconfig:"TLS_HOSTS" default:"localhost,127.0.0.1"`
+		TLSClientCAFile    string        `envconfig:"TLS_CLIENT_CA_FILE"`
+		TLSClientAuth      string        `envconfig:"TLS_CLIENT_AUTH" default:"none"`
 	}
 )
 
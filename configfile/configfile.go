@@ -0,0 +1,159 @@
+package configfile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+// extensions are the files picked up from a config directory
+var extensions = map[string]bool{
+	".yaml": true,
+	".yml":  true,
+	".json": true,
+}
+
+// Files expands a pattern into the config files it names, sorted.  The pattern is a file, a directory whose YAML and
+// JSON files are used, or a glob.  A pattern that names nothing is no files, not an error.
+func Files(pattern string) ([]string, error) {
+	info, err := os.Stat(pattern)
+
+	switch {
+	case err == nil && info.IsDir():
+		return dirFiles(pattern)
+	case err == nil:
+		return []string{pattern}, nil
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("bad config pattern %s: %w", pattern, err)
+	}
+
+	var files []string
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && !info.IsDir() {
+			files = append(files, match)
+		}
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func dirFiles(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		// Skip hidden entries, Kubernetes keeps the real files of a mounted ConfigMap in ..data directories
+		if strings.HasPrefix(entry.Name(), ".") || !extensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+			continue
+		}
+
+		file := filepath.Join(dir, entry.Name())
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			files = append(files, file)
+		}
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// Load decodes and merges every config file the pattern names.  A config name may only be used by one file.
+func Load(pattern string, strict bool) (spec.Configurations, error) {
+	files, err := Files(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	operations := spec.Configurations{}
+	sources := map[string]string{}
+
+	for _, file := range files {
+		contents, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		fileOperations := spec.Configurations{}
+		if err := spec.Decode(bytes.NewReader(contents), fileOperations, strict); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to decode %s: %w", file, err)
+		}
+
+		for name, operation := range fileOperations {
+			if source, ok := sources[name]; ok {
+				return nil, fmt.Errorf("config %s is in both %s and %s", name, source, file)
+			}
+
+			sources[name] = file
+			operations[name] = operation
+		}
+	}
+
+	return operations, nil
+}
+
+// Fingerprint identifies the current names and contents of the config files the pattern names
+func Fingerprint(pattern string) (string, error) {
+	files, err := Files(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	for _, file := range files {
+		contents, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(hash, "%s\x00%d\x00", file, len(contents))
+		hash.Write(contents)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Watch checks the config files the pattern names every interval and calls onChange whenever they differ from the
+// last check, until stop is closed.
+func Watch(pattern string, interval time.Duration, stop <-chan struct{}, onChange func()) {
+	last, _ := Fingerprint(pattern)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			current, err := Fingerprint(pattern)
+			if err != nil || current == last {
+				continue
+			}
+
+			last = current
+			onChange()
+		}
+	}
+}
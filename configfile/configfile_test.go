@@ -0,0 +1,90 @@
+package configfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Configfile", func() {
+	var dir string
+
+	write := func(name, contents string) string {
+		file := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(file), 0755)).ShouldNot(HaveOccurred())
+		Expect(ioutil.WriteFile(file, []byte(contents), 0644)).ShouldNot(HaveOccurred())
+
+		return file
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "gnockgnock")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		write("b.yaml", "second:\n  paths:\n    /second:\n      get:\n        statusCode: 200\n")
+		write("a.json", `{"first": {"paths": {"/first": {"get": {"statusCode": 200}}}}}`)
+		write("notes.txt", "not a config")
+		write("..data/hidden.yaml", "hidden: {}")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).ShouldNot(HaveOccurred())
+	})
+
+	Context("Finding files", func() {
+		It("Uses a single file as is", func() {
+			Expect(Files(filepath.Join(dir, "b.yaml"))).To(Equal([]string{filepath.Join(dir, "b.yaml")}))
+		})
+
+		It("Uses the YAML and JSON files of a directory", func() {
+			Expect(Files(dir)).To(Equal([]string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.yaml")}))
+		})
+
+		It("Expands globs", func() {
+			Expect(Files(filepath.Join(dir, "*.yaml"))).To(Equal([]string{filepath.Join(dir, "b.yaml")}))
+		})
+
+		It("Finds nothing for a missing file", func() {
+			Expect(Files(filepath.Join(dir, "missing.yaml"))).To(BeEmpty())
+		})
+	})
+
+	Context("Loading", func() {
+		It("Merges every file", func() {
+			operations, err := Load(dir, true)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(operations).To(HaveLen(2))
+			Expect(operations).To(HaveKey("first"))
+			Expect(operations).To(HaveKey("second"))
+		})
+
+		It("Rejects a config name used by two files", func() {
+			write("c.yaml", "first: {}")
+
+			_, err := Load(dir, true)
+			Expect(err).Should(MatchError(ContainSubstring("config first is in both")))
+		})
+	})
+
+	Context("Watching", func() {
+		It("Calls back when the files change", func() {
+			changes := make(chan struct{}, 1)
+			stop := make(chan struct{})
+			defer close(stop)
+
+			go Watch(dir, 10*time.Millisecond, stop, func() { changes <- struct{}{} })
+
+			Consistently(changes, 50*time.Millisecond).ShouldNot(Receive())
+
+			write("b.yaml", "second: {}")
+
+			Eventually(changes).Should(Receive())
+		})
+	})
+})
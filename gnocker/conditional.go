@@ -0,0 +1,162 @@
+package gnocker
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber"
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+type (
+	// conditional answers conditional requests for a response with an ETag and/or Last-Modified
+	conditional struct {
+		etag         string
+		autoETag     bool
+		lastModified time.Time
+	}
+)
+
+// newConditional is nil when the response has neither an ETag nor a Last-Modified
+func newConditional(options spec.Response) (*conditional, error) {
+	if options.ETag == "" && options.LastModified == "" {
+		return nil, nil
+	}
+
+	cond := &conditional{autoETag: options.ETag == spec.ETagAuto}
+
+	if !cond.autoETag && options.ETag != "" {
+		cond.etag = quoteETag(options.ETag)
+	}
+
+	if options.LastModified != "" {
+		lastModified, err := parseHTTPTime(options.LastModified)
+		if err != nil {
+			return nil, err
+		}
+
+		cond.lastModified = lastModified
+	}
+
+	return cond, nil
+}
+
+func parseHTTPTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC().Truncate(time.Second), nil
+	}
+
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 nor an HTTP date", value)
+	}
+
+	return t, nil
+}
+
+// quoteETag quotes a bare entity tag, leaving quoted and weak ones as they are
+func quoteETag(etag string) string {
+	if strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+
+	return `"` + etag + `"`
+}
+
+// apply sets the validators on a response that has been served, and replaces it with a 304 or 412 if the request's
+// conditions call for one.  Only successful responses are subject to conditions.
+func (cond *conditional) apply(c *fiber.Ctx) {
+	etag := cond.etag
+	if cond.autoETag {
+		sum := sha1.Sum(c.Fasthttp.Response.Body())
+		etag = `"` + hex.EncodeToString(sum[:]) + `"`
+	}
+
+	if etag != "" {
+		c.Set(fiber.HeaderETag, etag)
+	}
+
+	if !cond.lastModified.IsZero() {
+		c.Set(fiber.HeaderLastModified, cond.lastModified.Format(http.TimeFormat))
+	}
+
+	status := c.Fasthttp.Response.StatusCode()
+	if status < 200 || status > 299 {
+		return
+	}
+
+	safe := c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead
+
+	switch {
+	case !cond.preconditionHolds(c, etag):
+		replaceResponse(c, http.StatusPreconditionFailed)
+	case c.Get(fiber.HeaderIfNoneMatch) != "":
+		if etagListMatches(c.Get(fiber.HeaderIfNoneMatch), etag, false) {
+			if safe {
+				replaceResponse(c, http.StatusNotModified)
+			} else {
+				replaceResponse(c, http.StatusPreconditionFailed)
+			}
+		}
+	case safe && cond.notModifiedSince(c.Get(fiber.HeaderIfModifiedSince)):
+		replaceResponse(c, http.StatusNotModified)
+	}
+}
+
+// preconditionHolds checks If-Match, or If-Unmodified-Since when there's no If-Match
+func (cond *conditional) preconditionHolds(c *fiber.Ctx, etag string) bool {
+	if ifMatch := c.Get(fiber.HeaderIfMatch); ifMatch != "" {
+		return etagListMatches(ifMatch, etag, true)
+	}
+
+	if since, err := http.ParseTime(c.Get(fiber.HeaderIfUnmodifiedSince)); err == nil && !cond.lastModified.IsZero() {
+		return !cond.lastModified.After(since)
+	}
+
+	return true
+}
+
+func (cond *conditional) notModifiedSince(header string) bool {
+	since, err := http.ParseTime(header)
+	if err != nil || cond.lastModified.IsZero() {
+		return false
+	}
+
+	return !cond.lastModified.After(since)
+}
+
+// replaceResponse replaces the response's status and drops its body, keeping its headers
+func replaceResponse(c *fiber.Ctx, status int) {
+	c.Fasthttp.Response.ResetBody()
+	c.Status(status)
+}
+
+// etagListMatches reports whether an If-Match or If-None-Match header lists the etag.  Strong comparison, as If-Match
+// uses, never matches weak tags.
+func etagListMatches(header, etag string, strong bool) bool {
+	// Every mocked response has a current representation
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	if etag == "" || (strong && strings.HasPrefix(etag, "W/")) {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if strong && strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,93 @@
+package gnocker
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber"
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+type (
+	// responseCookie is a spec.Cookie ready to be set, its expiry either fixed or relative to when it's set
+	responseCookie struct {
+		cookie   fiber.Cookie
+		lifetime time.Duration
+	}
+)
+
+// sameSites are the SameSite modes a cookie can have, empty meaning lax
+var sameSites = map[string]bool{"": true, "lax": true, "strict": true, "none": true}
+
+func newResponseCookies(cookies []spec.Cookie) ([]responseCookie, error) {
+	compiled := make([]responseCookie, 0, len(cookies))
+
+	for i, cookie := range cookies {
+		rc, err := newResponseCookie(cookie)
+		if err != nil {
+			return nil, fmt.Errorf("cookie %d: %w", i, err)
+		}
+
+		compiled = append(compiled, rc)
+	}
+
+	return compiled, nil
+}
+
+func newResponseCookie(cookie spec.Cookie) (responseCookie, error) {
+	rc := responseCookie{cookie: fiber.Cookie{
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Path:     cookie.Path,
+		Domain:   cookie.Domain,
+		Secure:   cookie.Secure,
+		HTTPOnly: cookie.HTTPOnly,
+		SameSite: cookie.SameSite,
+	}}
+
+	if cookie.Name == "" {
+		return rc, fmt.Errorf("a cookie needs a name")
+	}
+
+	if !sameSites[strings.ToLower(cookie.SameSite)] {
+		return rc, fmt.Errorf("unknown sameSite %q, expected lax, strict or none", cookie.SameSite)
+	}
+
+	if cookie.Expires == "" {
+		return rc, nil
+	}
+
+	if lifetime, err := time.ParseDuration(cookie.Expires); err == nil {
+		rc.lifetime = lifetime
+		return rc, nil
+	}
+
+	expires, err := time.Parse(time.RFC3339, cookie.Expires)
+	if err != nil {
+		return rc, fmt.Errorf("expires %q is neither a duration nor an RFC 3339 time", cookie.Expires)
+	}
+	rc.cookie.Expires = expires
+
+	return rc, nil
+}
+
+// set adds the cookie to the response
+func (rc responseCookie) set(c *fiber.Ctx) {
+	cookie := rc.cookie
+	if rc.lifetime != 0 {
+		cookie.Expires = time.Now().Add(rc.lifetime)
+	}
+
+	c.Cookie(&cookie)
+}
+
+// requestCookies are the cookies sent with the request by name
+func requestCookies(c *fiber.Ctx) map[string]string {
+	cookies := map[string]string{}
+	c.Fasthttp.Request.Header.VisitAllCookie(func(key, value []byte) {
+		cookies[string(key)] = string(value)
+	})
+
+	return cookies
+}
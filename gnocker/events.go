@@ -0,0 +1,164 @@
+package gnocker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber"
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+type (
+	// serverEvent is an event of a stream, its fields in the order they are sent
+	serverEvent struct {
+		fields []responseValue
+		delay  time.Duration
+	}
+
+	// eventStream sends server-sent events over a connection kept open for them
+	eventStream struct {
+		events []serverEvent
+		loop   bool
+		// contentType is whether the response has a content type of its own, instead of text/event-stream
+		contentType bool
+	}
+)
+
+const mimeEventStream = "text/event-stream"
+
+// newEventStream parses the response's events, it's nil when there are none
+func newEventStream(configName string, options spec.Response, funcs map[string]interface{}) (*eventStream, error) {
+	if len(options.Events) == 0 {
+		return nil, nil
+	}
+
+	stream := &eventStream{loop: options.LoopEvents}
+	for _, headers := range options.Headers {
+		for header := range headers {
+			stream.contentType = stream.contentType || strings.EqualFold(header, fiber.HeaderContentType)
+		}
+	}
+
+	var waits time.Duration
+
+	for i, event := range options.Events {
+		e, err := newServerEvent(configName, event, funcs)
+		if err != nil {
+			return nil, fmt.Errorf("events[%d]: %w", i, err)
+		}
+
+		waits += e.delay
+		stream.events = append(stream.events, e)
+	}
+
+	if stream.loop && waits == 0 {
+		return nil, errors.New("looping events need a delay, or they would be sent as fast as they can be")
+	}
+
+	return stream, nil
+}
+
+func newServerEvent(configName string, event spec.Event, funcs map[string]interface{}) (serverEvent, error) {
+	e := serverEvent{}
+
+	if event.Delay != "" {
+		delay, err := time.ParseDuration(event.Delay)
+		if err != nil {
+			return serverEvent{}, fmt.Errorf("delay: %w", err)
+		}
+
+		e.delay = delay
+	}
+
+	if event.Retry < 0 {
+		return serverEvent{}, errors.New("retry can't be negative")
+	}
+
+	fields := [][2]string{{"event", event.Event}, {"id", event.ID}, {"data", event.Data}}
+	if event.Retry > 0 {
+		fields = append(fields, [2]string{"retry", strconv.Itoa(event.Retry)})
+	}
+
+	for _, field := range fields {
+		value, err := newResponseValue(configName, field[0], field[1], funcs)
+		if err != nil {
+			return serverEvent{}, fmt.Errorf("%s: %w", field[0], err)
+		}
+
+		e.fields = append(e.fields, value)
+	}
+
+	return e, nil
+}
+
+// apply has the response stream the events, rendering them with data, until they are all sent or done is closed.  A
+// stream that fails to render can only be cut short, failed is told why.
+func (s *eventStream) apply(c *fiber.Ctx, data interface{}, done <-chan struct{}, failed func(error)) {
+	if !s.contentType {
+		c.Set(fiber.HeaderContentType, mimeEventStream)
+	}
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Fasthttp.Response.ImmediateHeaderFlush = true
+
+	c.Fasthttp.SetBodyStreamWriter(func(w *bufio.Writer) {
+		for {
+			for _, event := range s.events {
+				select {
+				case <-done:
+					return
+				case <-time.After(event.delay):
+				}
+
+				rendered, err := event.render(data)
+				if err != nil {
+					failed(err)
+					return
+				}
+
+				// Flushing sends the event right away, a failure means the client is gone
+				if _, err := w.WriteString(rendered); err != nil {
+					return
+				}
+
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+
+			if !s.loop {
+				return
+			}
+		}
+	})
+}
+
+// render is the event as it's sent, a line per field and each line of data, ending with a blank line
+func (e serverEvent) render(data interface{}) (string, error) {
+	var rendered strings.Builder
+
+	for _, field := range e.fields {
+		value, err := field.render(data)
+		if err != nil {
+			return "", fmt.Errorf("event %s: %w", field.name, err)
+		}
+
+		if field.name != "data" {
+			if value != "" {
+				fmt.Fprintf(&rendered, "%s: %s\n", field.name, strings.ReplaceAll(value, "\n", " "))
+			}
+			continue
+		}
+
+		for _, line := range strings.Split(value, "\n") {
+			fmt.Fprintf(&rendered, "data: %s\n", line)
+		}
+	}
+
+	rendered.WriteString("\n")
+
+	return rendered.String(), nil
+}
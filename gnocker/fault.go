@@ -0,0 +1,82 @@
+package gnocker
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+
+	"github.com/gofiber/fiber"
+	"github.com/valyala/fasthttp"
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+type (
+	// fault takes over the connection of a response that has been prepared, given its header and body
+	fault func(conn net.Conn, raw net.Conn, header *fasthttp.ResponseHeader, body []byte)
+)
+
+var faults = map[string]fault{
+	spec.FaultReset: func(conn net.Conn, raw net.Conn, _ *fasthttp.ResponseHeader, _ []byte) {
+		// Without lingering the close that follows is a reset, TLS connections can only be closed
+		if tcp, ok := raw.(*net.TCPConn); ok {
+			_ = tcp.SetLinger(0)
+		}
+	},
+	spec.FaultClose: func(net.Conn, net.Conn, *fasthttp.ResponseHeader, []byte) {},
+	spec.FaultHeadersThenHang: func(conn net.Conn, _ net.Conn, header *fasthttp.ResponseHeader, body []byte) {
+		header.SetContentLength(len(body))
+		if _, err := conn.Write(header.Header()); err == nil {
+			waitForClient(conn)
+		}
+	},
+	spec.FaultTruncatedBody: func(conn net.Conn, _ net.Conn, header *fasthttp.ResponseHeader, body []byte) {
+		if len(body) == 0 {
+			body = []byte("gnock")
+		}
+
+		header.SetContentLength(len(body))
+		if _, err := conn.Write(header.Header()); err == nil {
+			_, _ = conn.Write(body[:len(body)/2])
+		}
+	},
+	spec.FaultMalformed: func(conn net.Conn, _ net.Conn, _ *fasthttp.ResponseHeader, _ []byte) {
+		_, _ = conn.Write([]byte("HTTP/1.1 gnock gnock\r\nwho's there?\r\n\r\n"))
+	},
+	spec.FaultHang: func(conn net.Conn, _ net.Conn, _ *fasthttp.ResponseHeader, _ []byte) {
+		waitForClient(conn)
+	},
+}
+
+func newFault(name string) (fault, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	f, ok := faults[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown fault %s", name)
+	}
+
+	return f, nil
+}
+
+// inject hands the connection over to the fault once the handler returns, instead of sending the response
+func (f fault) inject(c *fiber.Ctx) {
+	raw := c.Fasthttp.Conn()
+
+	// The context is reused once the handler returns, what the fault sends has to be copied out of it
+	header := &fasthttp.ResponseHeader{}
+	c.Fasthttp.Response.Header.CopyTo(header)
+	body := append([]byte{}, c.Fasthttp.Response.Body()...)
+
+	c.Fasthttp.HijackSetNoResponse(true)
+	c.Fasthttp.Hijack(func(conn net.Conn) {
+		f(conn, raw, header, body)
+	})
+}
+
+// waitForClient reads, and ignores, anything the client sends until it closes the connection
+func waitForClient(conn io.Reader) {
+	_, _ = io.Copy(ioutil.Discard, conn)
+}
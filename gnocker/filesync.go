@@ -0,0 +1,58 @@
+package gnocker
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+type (
+	// fileConfigs remembers the configs last loaded from files, so reloads only touch what changed in them
+	fileConfigs struct {
+		mu      sync.Mutex
+		configs spec.Configurations
+	}
+)
+
+// SyncFileConfigs brings the configs loaded from files in line with operations.  New and changed configs are added,
+// configs no longer in the files are removed and unchanged ones are left as they are, as are configs added any other
+// way.  It returns the names of the configs it added and removed.
+func (g *gnocker) SyncFileConfigs(operations spec.Configurations) (added, removed []string, err error) {
+	g.fileConfigs.mu.Lock()
+	defer g.fileConfigs.mu.Unlock()
+
+	changed := spec.Configurations{}
+	for name, operation := range operations {
+		if previous, ok := g.fileConfigs.configs[name]; ok && reflect.DeepEqual(previous, operation) {
+			continue
+		}
+
+		changed[name] = operation
+		added = append(added, name)
+	}
+
+	if err := g.AddConfig(changed); err != nil {
+		return nil, nil, err
+	}
+
+	for name, previous := range g.fileConfigs.configs {
+		if _, ok := operations[name]; ok {
+			continue
+		}
+
+		// Leave it be if something other than the files has replaced it since
+		if current, ok := g.Config(name); ok && reflect.DeepEqual(current, previous) {
+			g.RemoveConfig(name)
+			removed = append(removed, name)
+		}
+	}
+
+	g.fileConfigs.configs = operations
+
+	sort.Strings(added)
+	sort.Strings(removed)
+
+	return added, removed, nil
+}
@@ -0,0 +1,278 @@
+package gnocker
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+type (
+	// seededRand is a random source safe to share between the handlers of a config
+	seededRand struct {
+		mu  sync.Mutex
+		rnd *rand.Rand
+	}
+)
+
+const randStringLetters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+var (
+	fakeFirstNames = []string{
+		"Jean-Luc", "William", "Beverly", "Deanna", "Geordi", "Worf", "Data", "Kathryn", "Benjamin", "Kira",
+		"Jadzia", "Julian", "Miles", "Keiko", "Odo", "Quark", "Elim", "Tuvok", "Harry", "B'Elanna",
+	}
+	fakeLastNames = []string{
+		"Picard", "Riker", "Crusher", "Troi", "La Forge", "Janeway", "Sisko", "Nerys", "Dax", "Bashir",
+		"O'Brien", "Garak", "Paris", "Kim", "Torres", "Archer", "Tucker", "Reed", "Sato", "Mayweather",
+	}
+	fakeStreets = []string{
+		"Main St", "Oak Ave", "Maple Dr", "Cedar Ln", "Pine St", "Elm St", "Lakeview Rd", "Hillcrest Ave", "Park Pl",
+		"Sunset Blvd",
+	}
+	fakeCities = []string{
+		"Springfield", "Riverside", "Franklin", "Greenville", "Bristol", "Clinton", "Fairview", "Salem", "Madison",
+		"Georgetown",
+	}
+	fakeDomains = []string{"example.com", "example.net", "example.org"}
+)
+
+// newSeededRand is seeded with seed, or randomly when it is 0
+func newSeededRand(seed int64) *seededRand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &seededRand{rnd: rand.New(rand.NewSource(seed))}
+}
+
+// randFor is a random source of a response's own, for the draws of one purpose.  It's seeded from the config's seed
+// and the response, so how the response behaves doesn't depend on what else the config serves.
+func (config *compiledConfig) randFor(purpose string, options spec.Response) *seededRand {
+	if config.seed == 0 {
+		return newSeededRand(0)
+	}
+
+	// Encoding a response can't fail, and encodes maps in key order
+	encoded, _ := json.Marshal(options)
+
+	h := fnv.New64a()
+	_ = binary.Write(h, binary.BigEndian, config.seed)
+	_, _ = h.Write([]byte(purpose))
+	_, _ = h.Write(encoded)
+
+	seed := int64(h.Sum64())
+	if seed == 0 {
+		seed = config.seed
+	}
+
+	return newSeededRand(seed)
+}
+
+func (r *seededRand) intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.rnd.Intn(n)
+}
+
+func (r *seededRand) float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.rnd.Float64()
+}
+
+func (r *seededRand) normFloat64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.rnd.NormFloat64()
+}
+
+func (r *seededRand) read(b []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rnd.Read(b)
+}
+
+func (r *seededRand) pick(choices []string) string {
+	return choices[r.intn(len(choices))]
+}
+
+// templateFuncs are the functions available to templates, drawing their randomness from r
+func templateFuncs(r *seededRand) map[string]interface{} {
+	return map[string]interface{}{
+		"uuid": func() string {
+			b := make([]byte, 16)
+			r.read(b)
+			b[6] = b[6]&0x0f | 0x40
+			b[8] = b[8]&0x3f | 0x80
+
+			return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+		},
+		"now": func(layout ...string) string {
+			return formatTime(time.Now(), layout...)
+		},
+		"randInt": func(min, max int) (int, error) {
+			if max <= min {
+				return 0, fmt.Errorf("randInt needs max > min, got %d and %d", min, max)
+			}
+
+			return min + r.intn(max-min), nil
+		},
+		"randString": func(n int) string {
+			b := make([]byte, n)
+			for i := range b {
+				b[i] = randStringLetters[r.intn(len(randStringLetters))]
+			}
+
+			return string(b)
+		},
+		"base64": func(s string) string {
+			return base64.StdEncoding.EncodeToString([]byte(s))
+		},
+		"base64Decode": func(s string) (string, error) {
+			decoded, err := base64.StdEncoding.DecodeString(s)
+			return string(decoded), err
+		},
+		"jsonEscape": jsonEscape,
+		"toJSON": func(v interface{}) (string, error) {
+			encoded, err := json.Marshal(v)
+			return string(encoded), err
+		},
+		"sha256": func(s string) string {
+			sum := sha256.Sum256([]byte(s))
+			return hex.EncodeToString(sum[:])
+		},
+		"add": func(a, b interface{}) (interface{}, error) {
+			return arithmetic(a, b, func(x, y float64) (float64, error) { return x + y, nil })
+		},
+		"sub": func(a, b interface{}) (interface{}, error) {
+			return arithmetic(a, b, func(x, y float64) (float64, error) { return x - y, nil })
+		},
+		"mul": func(a, b interface{}) (interface{}, error) {
+			return arithmetic(a, b, func(x, y float64) (float64, error) { return x * y, nil })
+		},
+		"div": func(a, b interface{}) (interface{}, error) {
+			return arithmetic(a, b, func(x, y float64) (float64, error) {
+				if y == 0 {
+					return 0, errors.New("division by zero")
+				}
+				return x / y, nil
+			})
+		},
+		"mod": func(a, b interface{}) (interface{}, error) {
+			return arithmetic(a, b, func(x, y float64) (float64, error) {
+				if y == 0 {
+					return 0, errors.New("division by zero")
+				}
+				return math.Mod(x, y), nil
+			})
+		},
+		"fakeFirstName": func() string { return r.pick(fakeFirstNames) },
+		"fakeLastName":  func() string { return r.pick(fakeLastNames) },
+		"fakeName": func() string {
+			return r.pick(fakeFirstNames) + " " + r.pick(fakeLastNames)
+		},
+		"fakeEmail": func() string {
+			local := strings.NewReplacer(" ", "", "'", "", "-", "").Replace(
+				strings.ToLower(r.pick(fakeFirstNames) + "." + r.pick(fakeLastNames)))
+			return local + "@" + r.pick(fakeDomains)
+		},
+		"fakeAddress": func() string {
+			return fmt.Sprintf("%d %s, %s", 1+r.intn(9999), r.pick(fakeStreets), r.pick(fakeCities))
+		},
+		"fakeCity": func() string { return r.pick(fakeCities) },
+	}
+}
+
+// formatTime formats with a Go layout, the name of one of the time package's layouts, or unix for epoch seconds.
+// RFC3339 is the default.
+func formatTime(t time.Time, layout ...string) string {
+	if len(layout) == 0 {
+		return t.Format(time.RFC3339)
+	}
+
+	switch layout[0] {
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "unixMilli":
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+	case "RFC3339":
+		return t.Format(time.RFC3339)
+	case "RFC3339Nano":
+		return t.Format(time.RFC3339Nano)
+	case "RFC1123":
+		return t.Format(time.RFC1123)
+	case "http":
+		return t.UTC().Format(http.TimeFormat)
+	default:
+		return t.Format(layout[0])
+	}
+}
+
+// jsonEscape escapes a string to be put between quotes in JSON
+func jsonEscape(s string) string {
+	encoded, _ := json.Marshal(s)
+	return string(encoded[1 : len(encoded)-1])
+}
+
+// arithmetic applies op to two numbers, which may be numbers of any type or strings holding one, and answers with an
+// integer when the result is one
+func arithmetic(a, b interface{}, op func(x, y float64) (float64, error)) (interface{}, error) {
+	x, err := toFloat(a)
+	if err != nil {
+		return nil, err
+	}
+
+	y, err := toFloat(b)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := op(x, y)
+	if err != nil {
+		return nil, err
+	}
+
+	if result == math.Trunc(result) && math.Abs(result) < 1<<53 {
+		return int64(result), nil
+	}
+
+	return result, nil
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	case json.Number:
+		return n.Float64()
+	default:
+		f, err := strconv.ParseFloat(fmt.Sprint(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%v is not a number", v)
+		}
+
+		return f, nil
+	}
+}
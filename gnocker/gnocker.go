@@ -1,41 +1,59 @@
 package gnocker
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"html/template"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber"
 	"github.com/sirupsen/logrus"
+	"github.com/zerbitx/gnockgnock/certs"
 	"github.com/zerbitx/gnockgnock/encode"
 	"github.com/zerbitx/gnockgnock/spec"
 	"gopkg.in/yaml.v2"
 )
 
 type (
-	fiberBinding func(string, ...fiber.Handler) *fiber.Route
-
 	gnocker struct {
 		app             *fiber.App
+		admin           *fiber.App
 		configBasePath  string
-		handlers        map[string]map[string]map[string]fiber.Handler
-		configs         map[string]struct{}
-		handlerBases    map[string]fiberBinding
-		pathsSeen       map[string]bool
+		registry        *registry
+		ports           map[string]*portListener
+		fileConfigs     fileConfigs
 		logger          logrus.FieldLogger
+		journal         *journal
+		recorder        *recorder
+		proxyClient     *http.Client
+		proxy           string
+		strict          bool
+		tlsConfig       *tls.Config
+		authority       *certs.Authority
 		port            int
+		configPort      int
 		host            string
 		shouldOverwrite bool
+		// done is closed on shutdown, to end responses that would otherwise go on for as long as the client stays
+		done chan struct{}
 	}
 
 	config struct {
 		port           int
+		configPort     int
+		singlePort     bool
 		configBasePath string
 		host           string
 		logger         logrus.FieldLogger
 		overwrite      bool
+		journalSize    int
+		proxy          string
+		strict         bool
+		tlsConfig      *tls.Config
+		authority      *certs.Authority
 	}
 
 	// Option is a function that can modify a default config
@@ -47,71 +65,131 @@ const (
 	ConfigSelectHeader = "X-GNOCK-CONFIG"
 )
 
+// methods are the HTTP methods a path can be configured for
+var methods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodPost:    true,
+	http.MethodDelete:  true,
+	http.MethodPatch:   true,
+	http.MethodPut:     true,
+	http.MethodOptions: true,
+	http.MethodConnect: true,
+	http.MethodTrace:   true,
+	http.MethodHead:    true,
+}
+
 // New returns a new gnocker with a default setup of up app and config on 127.0.0.1 on ports 8080 & 8081
 func New(options ...Option) *gnocker {
 	logrus.SetReportCaller(true)
 	c := &config{
 		port:           8080,
+		configPort:     8081,
 		logger:         logrus.StandardLogger(),
 		host:           "127.0.0.1",
 		configBasePath: "/gnockconfig",
+		journalSize:    1000,
+		strict:         true,
 	}
 
 	for _, applyOption := range options {
 		applyOption(c)
 	}
 
-	app := fiber.New(&fiber.Settings{
-		ServerHeader:          "GnockGnock",
-		DisableStartupMessage: true,
-	})
+	app := newApp(0)
+
+	// In single port mode the config endpoints live alongside the mocked routes
+	admin := app
+	if !c.singlePort {
+		admin = newApp(0)
+	}
 
 	g := &gnocker{
 		logger:         c.logger,
+		journal:        newJournal(c.journalSize),
+		recorder:       newRecorder(),
+		proxyClient:    newProxyClient(),
+		proxy:          c.proxy,
+		strict:         c.strict,
+		tlsConfig:      c.tlsConfig,
+		authority:      c.authority,
 		app:            app,
+		admin:          admin,
 		port:           c.port,
+		configPort:     c.configPort,
 		host:           c.host,
 		configBasePath: c.configBasePath,
-		handlers:       map[string]map[string]map[string]fiber.Handler{},
-		configs:        map[string]struct{}{},
-		handlerBases: map[string]fiberBinding{
-			http.MethodGet:     app.Get,
-			http.MethodPost:    app.Post,
-			http.MethodDelete:  app.Delete,
-			http.MethodPatch:   app.Patch,
-			http.MethodPut:     app.Put,
-			http.MethodOptions: app.Options,
-			http.MethodConnect: app.Connect,
-			http.MethodTrace:   app.Trace,
-			http.MethodHead:    app.Head,
-		},
-		pathsSeen: map[string]bool{},
+		registry:       newRegistry(),
+		ports:          map[string]*portListener{},
+		done:           make(chan struct{}),
 	}
 
 	g.initConfigEndpoints()
 
+	// Every request that makes it past the config endpoints is routed by the registry
+	app.Use(g.serve(""))
+
 	return g
 }
 
-// Start starts both apps
+// newApp is a fiber app with gnock's settings, idleTimeout of 0 keeps idle connections open indefinitely
+func newApp(idleTimeout time.Duration) *fiber.App {
+	return fiber.New(&fiber.Settings{
+		ServerHeader:          "GnockGnock",
+		DisableStartupMessage: true,
+		// Values read from the context outlive the request in the journal
+		Immutable:   true,
+		IdleTimeout: idleTimeout,
+	})
+}
+
+// Start starts both apps, returning as soon as either stops
 func (g *gnocker) Start() error {
-	errc := make(chan error)
+	errc := make(chan error, 2)
 
 	// Start up our main server
 	go func() {
-		g.logger.WithFields(logrus.Fields{"host": g.host, "port": g.port}).Info("main")
-		errc <- g.app.Listen(fmt.Sprintf("%s:%d", g.host, g.port))
+		g.logger.WithFields(logrus.Fields{"host": g.host, "port": g.port, "tls": g.tlsConfig != nil}).Info("main")
+		errc <- g.app.Listen(fmt.Sprintf("%s:%d", g.host, g.port), g.listenTLS()...)
 	}()
 
+	// and the config server, unless it shares the main one
+	if g.admin != g.app {
+		go func() {
+			g.logger.WithFields(logrus.Fields{"host": g.host, "port": g.configPort}).Info("config")
+			errc <- g.admin.Listen(fmt.Sprintf("%s:%d", g.host, g.configPort))
+		}()
+	}
+
 	return <-errc
 }
 
-// Shutdown gracefully shuts down both apps
+// Shutdown gracefully shuts down both apps, and closes the ports of configs that have their own
 func (g *gnocker) Shutdown() error {
+	select {
+	case <-g.done:
+	default:
+		close(g.done)
+	}
+
+	_ = g.registry.update(func(next *snapshot) error {
+		for address, p := range g.ports {
+			p.close()
+			delete(g.ports, address)
+		}
+
+		return nil
+	})
+
 	if shutdownErr := g.app.Shutdown(); shutdownErr != nil {
 		return fmt.Errorf("failed to shutdown app %w", shutdownErr)
 	}
 
+	if g.admin != g.app {
+		if shutdownErr := g.admin.Shutdown(); shutdownErr != nil {
+			return fmt.Errorf("failed to shutdown config app %w", shutdownErr)
+		}
+	}
+
 	return nil
 }
 
@@ -136,6 +214,20 @@ func WithPort(port int) Option {
 	}
 }
 
+// WithConfigPort sets the config app's port
+func WithConfigPort(port int) Option {
+	return func(c *config) {
+		c.configPort = port
+	}
+}
+
+// WithSinglePort serves the config endpoints from the main app's port instead of their own
+func WithSinglePort(singlePort bool) Option {
+	return func(c *config) {
+		c.singlePort = singlePort
+	}
+}
+
 // WithConfigBasePath sets the base path to post and look up configurations
 func WithConfigBasePath(basePath string) Option {
 	return func(c *config) {
@@ -143,94 +235,277 @@ func WithConfigBasePath(basePath string) Option {
 	}
 }
 
+// WithStrictDecoding sets whether posted configs with unknown fields are rejected, which they are by default
+func WithStrictDecoding(strict bool) Option {
+	return func(c *config) {
+		c.strict = strict
+	}
+}
+
+// WithJournalSize sets how many served requests are kept in the journal, 0 disables it
+func WithJournalSize(size int) Option {
+	return func(c *config) {
+		c.journalSize = size
+	}
+}
+
 // AddConfig will wire in a new configuration with its own set of routes and responses associated with a config name for
-// header based differentiated access.
+// header based differentiated access.  A configuration with the name of an existing one replaces it.
+// Every configuration is validated and built before any is put in place, so either all of them are or none are.  When
+// validation fails the error is the ValidationErrors found.
 func (g *gnocker) AddConfig(operations spec.Configurations) error {
+	if problems := g.Validate(operations); len(problems) > 0 {
+		return problems
+	}
+
+	compiled := make([]*compiledConfig, 0, len(operations))
+
 	for configName, operation := range operations {
-		g.handlers[configName] = map[string]map[string]fiber.Handler{}
+		config, err := g.compileConfig(configName, operation)
+		if err != nil {
+			return err
+		}
 
-		var err error
-		if err = g.scheduleConfigExpire(configName, operation); err != nil {
+		compiled = append(compiled, config)
+	}
+
+	return g.registry.update(func(next *snapshot) error {
+		for _, config := range compiled {
+			next.put(config)
+		}
+
+		if err := g.syncPorts(next); err != nil {
 			return err
 		}
 
-		// Wire each path up to its method and response configurations
-		for path, methods := range operation.Paths {
-			for m, options := range methods {
-				method := strings.ToUpper(m)
+		for _, config := range compiled {
+			g.stopConfigExpire(config.name)
+			g.scheduleConfigExpire(config.name, config.ttl)
+		}
+
+		return nil
+	})
+}
+
+// compileConfig builds the handlers of every path and method in a configuration
+func (g *gnocker) compileConfig(configName string, operation spec.Configuration) (*compiledConfig, error) {
+	ttl, err := parseTTL(operation.TTL)
+	if err != nil {
+		g.logger.WithError(err).Error()
+		return nil, err
+	}
 
-				g.logger.WithFields(logrus.Fields{
-					"config": configName,
-					"path":   path,
-					"method": method,
-				}).Debug("wiring")
+	config := &compiledConfig{
+		name:     configName,
+		spec:     operation,
+		proxy:    strings.TrimSuffix(operation.Proxy, "/"),
+		ttl:      ttl,
+		address:  g.configAddress(operation.Host, operation.Port),
+		seed:     operation.Seed,
+		funcs:    templateFuncs(newSeededRand(operation.Seed)),
+		scenario: newScenario(operation.States),
+	}
 
-				if _, ok := g.handlers[configName][path]; !ok {
-					g.handlers[configName][path] = map[string]fiber.Handler{}
-				}
+	routes := map[string]*route{}
 
-				var delay time.Duration = 0
-				if options.Delay != "" {
-					if delay, err = time.ParseDuration(options.Delay); err != nil {
-						g.logger.WithError(err).Error("Failed to parse delay duration")
-						return err
-					}
-					options.DelayDuration = delay
-				}
+	// Resources come first, so paths can override any of their routes
+	for name, resource := range operation.Resources {
+		store, err := newResourceStore(name, resource)
+		if err != nil {
+			return nil, fmt.Errorf("resource %s: %w", name, err)
+		}
 
-				handler, err := g.handler(configName, options)
-				if err != nil {
-					return err
-				}
+		for _, r := range store.routes() {
+			routes[r.path] = r
+		}
+	}
 
-				g.handlers[configName][path][method] = handler
-
-				// We only need to map the path and method once.
-				// The specific handler by config name will be found therein.
-				// Would use app.All, but we need the understanding of params to be parsed by fiber
-				if _, seen := g.pathsSeen[method+":"+path]; !seen {
-					g.pathsSeen[method+":"+path] = true
-
-					// Add the handler, closing over the current values.
-					go func(path, method, configName string) {
-						g.handlerBases[method](path, func(c *fiber.Ctx) {
-							// If no config name was sent, serve the first path configured by this instance
-							// otherwise look up the correct handler by the config name header sent.
-							var handler map[string]fiber.Handler
-							servingConfig := configName
-							if configFromHeader := c.Get(ConfigSelectHeader); configFromHeader != "" {
-								servingConfig = configFromHeader
-							}
-
-							g.logger.WithFields(logrus.Fields{
-								"config": servingConfig,
-								"path":   c.Path(),
-								"method": method,
-							}).Debug("serving")
-
-							handler = g.handlers[servingConfig][path]
-
-							if handler != nil && handler[c.Method()] != nil {
-								handler[c.Method()](c)
-							} else {
-								g.logger.WithError(err).Error("failed to find handler")
-								c.SendStatus(http.StatusNotFound)
-							}
-						})
-					}(path, method, configName)
-				}
+	// Wire each path up to its method and response configurations
+	for path, methodResponses := range operation.Paths {
+		r, ok := routes[path]
+		if !ok {
+			r = &route{path: path, pattern: newPathPattern(path), handlers: map[string]fiber.Handler{}}
+			routes[path] = r
+		}
+
+		for m, options := range methodResponses {
+			method := strings.ToUpper(m)
+
+			g.logger.WithFields(logrus.Fields{
+				"config": configName,
+				"path":   path,
+				"method": method,
+			}).Debug("wiring")
+
+			if !methods[method] {
+				return nil, fmt.Errorf("unknown method %s for %s", m, path)
 			}
+
+			handler, err := g.handler(config, options)
+			if err != nil {
+				return nil, err
+			}
+
+			r.handlers[method] = handler
 		}
 	}
 
-	return nil
+	for _, r := range routes {
+		config.routes = append(config.routes, r)
+	}
+
+	sortRoutes(config.routes)
+
+	return config, nil
+}
+
+// serve is the handler for the configs at an address, empty for the main port.  It finds the config and route for a
+// request and hands it to the route's handler.  The config is the one named in the config select header, or when none is
+// sent the first config added with a route for the request.
+func (g *gnocker) serve(address string) fiber.Handler {
+	return func(c *fiber.Ctx) {
+		g.serveAt(c, address)
+	}
+}
+
+func (g *gnocker) serveAt(c *fiber.Ctx, address string) {
+	configName := c.Get(ConfigSelectHeader)
+	config, r, params := g.registry.load().lookup(address, configName, c.Method(), c.Path())
+
+	if config != nil {
+		configName = config.name
+	}
+
+	var routePath string
+	if r != nil {
+		routePath = r.path
+	}
+
+	g.logger.WithFields(logrus.Fields{
+		"config": configName,
+		"path":   c.Path(),
+		"method": c.Method(),
+	}).Debug("serving")
+
+	if r != nil {
+		c.Locals(routeParamsLocal, params)
+		r.handlerFor(c.Method())(c)
+	} else {
+		g.logger.WithField("config", configName).Debug("failed to find handler")
+		g.proxyOrNotFound(c, address, configName)
+	}
+
+	g.journal.record(journalEntry(c, configName, routePath))
+}
+
+// Config returns the named configuration as it was added
+func (g *gnocker) Config(configName string) (spec.Configuration, bool) {
+	config, ok := g.registry.load().configs[configName]
+	if !ok {
+		return spec.Configuration{}, false
+	}
+
+	return config.spec, true
+}
+
+// ConfigNames returns the name of every configuration in the order they were added
+func (g *gnocker) ConfigNames() []string {
+	return append([]string{}, g.registry.load().order...)
+}
+
+// RemoveConfig removes a configuration and its handlers, reporting whether there was one to remove.
+// Paths it was the default for fall to the next config, in the order they were added, that has them.
+func (g *gnocker) RemoveConfig(configName string) bool {
+	var removed bool
+
+	_ = g.registry.update(func(next *snapshot) error {
+		g.stopConfigExpire(configName)
+		removed = next.remove(configName)
+
+		return g.syncPorts(next)
+	})
+
+	return removed
+}
+
+// RemoveAllConfigs removes every configuration
+func (g *gnocker) RemoveAllConfigs() {
+	_ = g.registry.update(func(next *snapshot) error {
+		for _, configName := range next.order {
+			g.stopConfigExpire(configName)
+		}
+
+		next.configs = map[string]*compiledConfig{}
+		next.order = nil
+
+		return g.syncPorts(next)
+	})
 }
 
-func (g *gnocker) handler(configName string, options spec.Response) (func(c *fiber.Ctx), error) {
-	var tpl *template.Template
+func (g *gnocker) handler(config *compiledConfig, options spec.Response) (func(c *fiber.Ctx), error) {
+	if options.Match != nil || options.State != "" || len(options.Candidates) > 0 {
+		return g.matchingHandler(config, options)
+	}
+
+	// Only requests the response is for can fail, so this comes once matching has picked it
+	if options.FailureRate > 0 {
+		return g.failing(config, options)
+	}
+
+	if len(options.Sequence) > 0 {
+		return g.sequenceHandler(config, options)
+	}
+
+	if options.WebSocket != nil {
+		return g.webSocketHandler(config, options)
+	}
+
+	var tpl, statusTpl responseTemplate
 	var err error
+
+	if options.Delay != "" {
+		if options.DelayDuration, err = time.ParseDuration(options.Delay); err != nil {
+			g.logger.WithError(err).Error("Failed to parse delay duration")
+			return nil, err
+		}
+	}
+
+	wait, err := newLatency(options, config.randFor("latency", options))
+	if err != nil {
+		g.logger.WithError(err).Error("Failed to parse latency")
+		return nil, err
+	}
+
+	cookies, err := newResponseCookies(options.Cookies)
+	if err != nil {
+		g.logger.WithError(err).Error("Failed to parse cookies")
+		return nil, err
+	}
+
+	cond, err := newConditional(options)
+	if err != nil {
+		g.logger.WithError(err).Error("Failed to parse last modified")
+		return nil, err
+	}
+
+	f, err := newFault(options.Fault)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := newThrottle(options.Throttle)
+	if err != nil {
+		g.logger.WithError(err).Error("Failed to parse throttle")
+		return nil, err
+	}
+
 	if options.BodyTemplate != "" {
-		tpl, err = template.New(configName).Parse(options.BodyTemplate)
+		engine, err := templateEngine(options)
+		if err != nil {
+			return nil, err
+		}
+
+		tpl, err = parseTemplate(config.name, engine, options.BodyTemplate, config.funcs)
 
 		if err != nil {
 			g.logger.
@@ -241,79 +516,186 @@ func (g *gnocker) handler(configName string, options spec.Response) (func(c *fib
 		}
 	}
 
-	return func(c *fiber.Ctx) {
-		c.Status(options.StatusCode)
+	if options.StatusCodeTemplate != "" {
+		statusTpl, err = parseTemplate(config.name, spec.TemplateEngineText, options.StatusCodeTemplate, config.funcs)
+		if err != nil {
+			g.logger.WithError(err).Error("Failed to parse status code template")
+			return nil, err
+		}
+	}
 
-		for _, headers := range options.Headers {
-			for header, value := range headers {
-				c.Fasthttp.Response.Header.Add(header, value)
+	headers, err := newResponseHeaders(config.name, options.Headers, config.funcs)
+	if err != nil {
+		g.logger.WithError(err).Error("Failed to parse header template")
+		return nil, err
+	}
+
+	stream, err := newEventStream(config.name, options, config.funcs)
+	if err != nil {
+		g.logger.WithError(err).Error("Failed to parse events")
+		return nil, err
+	}
+
+	templated := tpl != nil || statusTpl != nil
+	for _, header := range headers {
+		templated = templated || header.tpl != nil
+	}
+
+	if stream != nil {
+		for _, event := range stream.events {
+			for _, field := range event.fields {
+				templated = templated || field.tpl != nil
 			}
 		}
+	}
 
-		// Wait the configured delay, or 0/immediate if none
-		time.Sleep(options.DelayDuration)
+	return func(c *fiber.Ctx) {
+		var data map[string]interface{}
+		if templated {
+			data = templateData(c, config.name)
+		}
 
-		// If a template was configured and parsed, correctly
-		if tpl != nil {
-			templateVars := map[string]string{}
+		status := options.StatusCode
+		if statusTpl != nil {
+			rendered, err := renderStatusCode(statusTpl, data)
+			if err != nil {
+				g.templateFailed(c, err)
+				return
+			}
 
-			// populate the template data from the params
-			for _, name := range c.Route().Params {
-				templateVars[name] = c.Params(name)
+			status = rendered
+		}
+
+		c.Status(status)
+
+		for _, header := range headers {
+			value, err := header.render(data)
+			if err != nil {
+				g.templateFailed(c, err)
+				return
 			}
 
-			c.Fasthttp.Response.BodyWriter()
-			err := tpl.Execute(c.Fasthttp.Response.BodyWriter(), templateVars)
+			c.Fasthttp.Response.Header.Add(header.name, value)
+		}
+
+		for _, cookie := range cookies {
+			cookie.set(c)
+		}
+
+		// Wait the configured delay or latency, or 0/immediate if none
+		time.Sleep(wait())
+
+		if stream != nil {
+			stream.apply(c, data, g.done, func(err error) {
+				g.logger.WithError(err).Error("failed to render event")
+			})
+		} else if tpl != nil {
+			// If a template was configured and parsed, correctly
+			err := tpl.Execute(c.Fasthttp.Response.BodyWriter(), data)
 
 			if err != nil {
-				g.logger.WithError(err).Error("failed to execute template")
-				c.Send("Gnock gnock has failed you.  This is likely not your fault: " + err.Error())
-				c.SendStatus(http.StatusInternalServerError)
+				g.templateFailed(c, err)
 				return
 			}
 		} else if options.Body != "" {
 			// otherwise use the static response
 			c.Send(options.Body)
 		}
+
+		// Reading a streamed body to check it against the request would drain the stream
+		if cond != nil && stream == nil {
+			cond.apply(c)
+		}
+
+		if f != nil {
+			f.inject(c)
+		} else if t != nil {
+			t.apply(c)
+		}
+
+		if options.NextState != "" {
+			if err := config.scenario.moveTo(options.NextState); err != nil {
+				g.logger.WithError(err).Error("failed to move scenario")
+			}
+		}
 	}, nil
 }
 
-func (g *gnocker) scheduleConfigExpire(configName string, operation spec.Configuration) error {
-	if operation.TTL == "" {
-		return nil
+// templateFailed responds with a server error when a template can't be executed
+func (g *gnocker) templateFailed(c *fiber.Ctx, err error) {
+	g.logger.WithError(err).Error("failed to execute template")
+	c.Send("Gnock gnock has failed you.  This is likely not your fault: " + err.Error())
+	c.SendStatus(http.StatusInternalServerError)
+}
+
+func parseTTL(ttl string) (time.Duration, error) {
+	if ttl == "" {
+		return 0, nil
 	}
 
-	dur, err := time.ParseDuration(operation.TTL)
+	dur, err := time.ParseDuration(ttl)
 
 	if err != nil {
-		g.logger.WithError(err).Error()
-		return fmt.Errorf("failed to parse duration %s", operation.TTL)
+		return 0, fmt.Errorf("failed to parse duration %s", ttl)
 	}
 
-	go func() {
-		<-time.After(dur)
-		g.logger.WithField("config", configName).Info("Removing expired")
-		delete(g.handlers, configName)
-	}()
+	return dur, nil
+}
 
-	return nil
+// scheduleConfigExpire removes a config once its TTL is up, it must be called from within a registry update
+func (g *gnocker) scheduleConfigExpire(configName string, ttl time.Duration) {
+	if ttl == 0 {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(ttl, func() {
+		_ = g.registry.update(func(next *snapshot) error {
+			// A replaced config has its own TTL
+			if g.registry.expirations[configName] != timer {
+				return nil
+			}
+
+			g.logger.WithField("config", configName).Info("Removing expired")
+			delete(g.registry.expirations, configName)
+			next.remove(configName)
+
+			return g.syncPorts(next)
+		})
+	})
+
+	g.registry.expirations[configName] = timer
+}
+
+// stopConfigExpire cancels the TTL of a config, it must be called from within a registry update
+func (g *gnocker) stopConfigExpire(configName string) {
+	if timer, ok := g.registry.expirations[configName]; ok {
+		timer.Stop()
+		delete(g.registry.expirations, configName)
+	}
 }
 
 func (g *gnocker) initConfigEndpoints() {
+	g.initJournalEndpoints()
+	g.initRecordingEndpoints()
+	g.initTLSEndpoints()
+	g.initScenarioEndpoints()
+
 	g.logger.
 		WithFields(logrus.Fields{
 			http.MethodPost: g.configBasePath,
 			http.MethodGet:  g.configBasePath,
 		}).Debug("config endpoints")
 
-	g.app.Post(g.configBasePath, func(c *fiber.Ctx) {
+	g.admin.Post(g.configBasePath, func(c *fiber.Ctx) {
 		bodyReader := strings.NewReader(c.Body())
 
 		newOperations := spec.Configurations{}
-		err := yaml.NewDecoder(bodyReader).Decode(newOperations)
+		err := spec.Decode(bodyReader, newOperations, g.strictFor(c))
 
 		if err != nil {
 			g.logger.WithError(err).Error("failed to decode yaml")
+			c.Send(err.Error())
 			c.SendStatus(http.StatusBadRequest)
 			return
 		}
@@ -322,14 +704,12 @@ func (g *gnocker) initConfigEndpoints() {
 
 		if err != nil {
 			g.logger.WithError(err).Error("failed to add request")
-			c.Send(err.Error())
-			c.SendStatus(http.StatusBadRequest)
+			g.sendConfigError(c, err)
 			return
 		}
 
 		var configNames []string
 		for name := range newOperations {
-			g.configs[name] = struct{}{}
 			configNames = append(configNames, name)
 		}
 
@@ -344,13 +724,8 @@ func (g *gnocker) initConfigEndpoints() {
 		}
 	})
 
-	g.app.Get(g.configBasePath, func(c *fiber.Ctx) {
-		var configs []string
-		for config := range g.configs {
-			configs = append(configs, config)
-		}
-
-		err := encode.JSONIndented(configs, c.Fasthttp.Response.BodyWriter())
+	g.admin.Get(g.configBasePath, func(c *fiber.Ctx) {
+		err := encode.JSONIndented(g.ConfigNames(), c.Fasthttp.Response.BodyWriter())
 
 		if err != nil {
 			g.logger.WithError(err).Error("Failed to encode response")
@@ -358,4 +733,185 @@ func (g *gnocker) initConfigEndpoints() {
 			return
 		}
 	})
+
+	g.admin.Delete(g.configBasePath, func(c *fiber.Ctx) {
+		g.RemoveAllConfigs()
+		c.SendStatus(http.StatusNoContent)
+	})
+
+	configPath := g.configBasePath + "/:name"
+
+	g.admin.Get(configPath, func(c *fiber.Ctx) {
+		configuration, ok := g.Config(c.Params("name"))
+		if !ok {
+			c.SendStatus(http.StatusNotFound)
+			return
+		}
+
+		g.sendEncoded(c, configuration)
+	})
+
+	g.admin.Put(configPath, func(c *fiber.Ctx) {
+		configuration := spec.Configuration{}
+		if err := spec.Decode(strings.NewReader(c.Body()), &configuration, g.strictFor(c)); err != nil {
+			g.logger.WithError(err).Error("failed to decode yaml")
+			c.Send(err.Error())
+			c.SendStatus(http.StatusBadRequest)
+			return
+		}
+
+		g.replaceConfig(c, configuration)
+	})
+
+	g.admin.Patch(configPath, func(c *fiber.Ctx) {
+		configuration, ok := g.Config(c.Params("name"))
+		if !ok {
+			c.SendStatus(http.StatusNotFound)
+			return
+		}
+
+		patch := spec.Configuration{}
+		if err := spec.Decode(strings.NewReader(c.Body()), &patch, g.strictFor(c)); err != nil {
+			g.logger.WithError(err).Error("failed to decode yaml")
+			c.Send(err.Error())
+			c.SendStatus(http.StatusBadRequest)
+			return
+		}
+
+		g.replaceConfig(c, mergeConfig(configuration, patch))
+	})
+
+	g.admin.Delete(configPath, func(c *fiber.Ctx) {
+		if !g.RemoveConfig(c.Params("name")) {
+			c.SendStatus(http.StatusNotFound)
+			return
+		}
+
+		c.SendStatus(http.StatusNoContent)
+	})
+}
+
+// replaceConfig puts a configuration in place under the name in the path, responding with what is now configured
+func (g *gnocker) replaceConfig(c *fiber.Ctx, configuration spec.Configuration) {
+	name := c.Params("name")
+
+	if err := g.AddConfig(spec.Configurations{name: configuration}); err != nil {
+		g.logger.WithError(err).Error("failed to replace config")
+		g.sendConfigError(c, err)
+		return
+	}
+
+	configuration, _ = g.Config(name)
+	g.sendEncoded(c, configuration)
+}
+
+// strictFor decides whether a posted document is decoded strictly, the strict query parameter overrides the default
+func (g *gnocker) strictFor(c *fiber.Ctx) bool {
+	if strict, err := strconv.ParseBool(c.Query("strict")); err == nil {
+		return strict
+	}
+
+	return g.strict
+}
+
+// sendConfigError responds with a bad request, listing every problem if the configs didn't validate
+func (g *gnocker) sendConfigError(c *fiber.Ctx, err error) {
+	c.Status(http.StatusBadRequest)
+
+	var problems ValidationErrors
+	if !errors.As(err, &problems) {
+		c.Send(err.Error())
+		return
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if encodeErr := encode.JSONIndented(problems, c.Fasthttp.Response.BodyWriter()); encodeErr != nil {
+		g.logger.WithError(encodeErr).Error("Failed to encode response")
+		c.SendStatus(http.StatusInternalServerError)
+	}
+}
+
+// sendEncoded responds with YAML if it was asked for by format query or Accept header, JSON otherwise
+func (g *gnocker) sendEncoded(c *fiber.Ctx, v interface{}) {
+	var err error
+	if c.Query("format") == "yaml" || strings.Contains(c.Get(fiber.HeaderAccept), "yaml") {
+		c.Set(fiber.HeaderContentType, "application/x-yaml")
+		err = yaml.NewEncoder(c.Fasthttp.Response.BodyWriter()).Encode(v)
+	} else {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		err = encode.JSONIndented(v, c.Fasthttp.Response.BodyWriter())
+	}
+
+	if err != nil {
+		g.logger.WithError(err).Error("Failed to encode response")
+		c.SendStatus(http.StatusInternalServerError)
+	}
+}
+
+// mergeConfig applies a partial configuration on top of an existing one.  Set fields replace existing ones and paths
+// are merged method by method.
+func mergeConfig(configuration, patch spec.Configuration) spec.Configuration {
+	merged := configuration
+	merged.Paths = map[string]spec.Responses{}
+
+	if patch.TTL != "" {
+		merged.TTL = patch.TTL
+	}
+
+	if patch.Proxy != "" {
+		merged.Proxy = patch.Proxy
+	}
+
+	if patch.Port != 0 {
+		merged.Port = patch.Port
+	}
+
+	if patch.Host != "" {
+		merged.Host = patch.Host
+	}
+
+	if patch.Seed != 0 {
+		merged.Seed = patch.Seed
+	}
+
+	if len(patch.States) > 0 {
+		merged.States = patch.States
+	}
+
+	if len(patch.Resources) > 0 {
+		merged.Resources = map[string]spec.Resource{}
+		for name, resource := range configuration.Resources {
+			merged.Resources[name] = resource
+		}
+
+		for name, resource := range patch.Resources {
+			merged.Resources[name] = resource
+		}
+	}
+
+	for path, methods := range configuration.Paths {
+		merged.Paths[path] = spec.Responses{}
+		for method, response := range methods {
+			merged.Paths[path][method] = response
+		}
+	}
+
+	for path, methods := range patch.Paths {
+		if _, ok := merged.Paths[path]; !ok {
+			merged.Paths[path] = spec.Responses{}
+		}
+
+		for method, response := range methods {
+			// Methods are case insensitive, the patch's replaces the config's however either is written
+			for existing := range merged.Paths[path] {
+				if strings.EqualFold(existing, method) {
+					delete(merged.Paths[path], existing)
+				}
+			}
+
+			merged.Paths[path][method] = response
+		}
+	}
+
+	return merged
 }
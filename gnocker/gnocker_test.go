@@ -1,14 +1,25 @@
 package gnocker
 
 import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/fasthttp/websocket"
+	"github.com/zerbitx/gnockgnock/certs"
 	"github.com/zerbitx/gnockgnock/spec"
 
 	. "github.com/onsi/ginkgo"
@@ -18,29 +29,32 @@ import (
 var _ = Describe("Gnocker", func() {
 	client := http.Client{Timeout: time.Second * 3}
 	port := 1701
+	configPort := 1702
 	var app *gnocker
 
 	BeforeSuite(func() {
 		logrus.SetOutput(ioutil.Discard)
-		app = New(WithPort(port))
+		app = New(WithPort(port), WithConfigPort(configPort))
 
 		go func() {
 			err := app.Start()
 			Expect(err).ShouldNot(HaveOccurred())
 		}()
 
-		// Wait for the server to start
-		Eventually(func() error {
-			req, err := http.NewRequest(
-				http.MethodGet,
-				fmt.Sprintf("http://127.0.0.1:%d", port),
-				nil)
+		// Wait for both servers to start
+		for _, p := range []int{port, configPort} {
+			Eventually(func() error {
+				req, err := http.NewRequest(
+					http.MethodGet,
+					fmt.Sprintf("http://127.0.0.1:%d", p),
+					nil)
 
-			Expect(err).ShouldNot(HaveOccurred())
+				Expect(err).ShouldNot(HaveOccurred())
 
-			_, err = client.Do(req)
-			return err
-		}).ShouldNot(HaveOccurred())
+				_, err = client.Do(req)
+				return err
+			}).ShouldNot(HaveOccurred())
+		}
 	})
 
 	AfterSuite(func() {
@@ -97,8 +111,8 @@ var _ = Describe("Gnocker", func() {
 
 			Expect(err).ShouldNot(HaveOccurred())
 			res, err := client.Do(req)
-			defer res.Body.Close()
 			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
 			Expect(res.StatusCode).To(Equal(http.StatusTeapot))
 
 			resBytes, err := ioutil.ReadAll(res.Body)
@@ -116,7 +130,7 @@ var _ = Describe("Gnocker", func() {
 
 					req, err := http.NewRequest(
 						http.MethodPost,
-						fmt.Sprintf("http://127.0.0.1:%d/gnockconfig", port),
+						fmt.Sprintf("http://127.0.0.1:%d/gnockconfig", configPort),
 						cfgFile,
 					)
 
@@ -163,7 +177,7 @@ var _ = Describe("Gnocker", func() {
 
 				req, err := http.NewRequest(
 					http.MethodPost,
-					fmt.Sprintf("http://127.0.0.1:%d/gnockconfig", port),
+					fmt.Sprintf("http://127.0.0.1:%d/gnockconfig", configPort),
 					cfgFile,
 				)
 
@@ -228,14 +242,90 @@ var _ = Describe("Gnocker", func() {
 
 			Expect(err).ShouldNot(HaveOccurred())
 			res, err := client.Do(req)
-			defer res.Body.Close()
 			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
 			Expect(res.StatusCode).To(Equal(http.StatusOK))
 
 			resBytes, err := ioutil.ReadAll(res.Body)
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(string(resBytes)).To(Equal("The Enterprise is Galaxy class."))
 		})
+
+		It("Exposes the request to the template", func() {
+			err := app.AddConfig(spec.Configurations{
+				"templatedRequest": spec.Configuration{
+					Paths: map[string]spec.Responses{
+						"/templated/:id": map[string]spec.Response{
+							http.MethodPost: {
+								BodyTemplate: "{{.Config}} {{.Method}} {{.Path}} {{.Params.id}}={{.id}} " +
+									`{{.Query.q}} {{index .Headers "X-Request-Id"}} {{.JSON.user.name}} {{index .JSON.user.roles 1}}`,
+								StatusCode: http.StatusOK,
+							},
+						},
+					},
+				},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				fmt.Sprintf("http://127.0.0.1:%d/templated/42?q=search", port),
+				strings.NewReader(`{"user": {"name": "Picard", "roles": ["captain", "diplomat"]}}`),
+			)
+			Expect(err).ShouldNot(HaveOccurred())
+			req.Header.Set("X-Request-Id", "req-1")
+			req.Header.Set("Content-Type", "application/json")
+
+			res, err := client.Do(req)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+			resBytes, err := ioutil.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(string(resBytes)).To(Equal("templatedRequest POST /templated/42 42=42 search req-1 Picard diplomat"))
+		})
+
+		It("Splits params on dashes and dots, and anchors a wildcard to what follows it", func() {
+			err := app.AddConfig(spec.Configurations{
+				"delimitedParams": spec.Configuration{
+					Paths: map[string]spec.Responses{
+						"/file/:name.:ext": map[string]spec.Response{
+							http.MethodGet: {BodyTemplate: "{{.name}} {{.ext}}", StatusCode: http.StatusOK},
+						},
+						"/flights/:from-:to": map[string]spec.Response{
+							http.MethodGet: {BodyTemplate: "{{.from}} {{.to}}", StatusCode: http.StatusOK},
+						},
+						"/api/*/end": map[string]spec.Response{
+							http.MethodGet: {BodyTemplate: `{{index .Params "*"}}`, StatusCode: http.StatusOK},
+						},
+					},
+				},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			for path, expected := range map[string]string{
+				"/file/report.pdf": "report pdf",
+				"/flights/LHR-JFK": "LHR JFK",
+				"/api/x/end":       "x",
+				"/api/x/y/end":     "x/y",
+			} {
+				res, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d%s", port, path))
+				Expect(err).ShouldNot(HaveOccurred())
+				body, err := ioutil.ReadAll(res.Body)
+				res.Body.Close()
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(res.StatusCode).To(Equal(http.StatusOK), path)
+				Expect(string(body)).To(Equal(expected), path)
+			}
+
+			for _, path := range []string{"/api/x/other", "/api/end/x", "/file/report"} {
+				res, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d%s", port, path))
+				Expect(err).ShouldNot(HaveOccurred())
+				res.Body.Close()
+				Expect(res.StatusCode).To(Equal(http.StatusNotFound), path)
+			}
+		})
 	})
 
 	Context("With a TTL", func() {
@@ -337,4 +427,1971 @@ var _ = Describe("Gnocker", func() {
 			Expect(res.StatusCode).To(Equal(http.StatusTeapot))
 		}, 2250)
 	})
+
+	Context("With a response sequence", func() {
+		statusesFor := func(path string, calls int) []int {
+			var statuses []int
+			for i := 0; i < calls; i++ {
+				req, err := http.NewRequest(
+					http.MethodGet,
+					fmt.Sprintf("http://127.0.0.1:%d%s", port, path),
+					nil,
+				)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				res, err := client.Do(req)
+				Expect(err).ShouldNot(HaveOccurred())
+				res.Body.Close()
+
+				statuses = append(statuses, res.StatusCode)
+			}
+
+			return statuses
+		}
+
+		sequenceConfig := func(name, path, mode string) spec.Configurations {
+			return spec.Configurations{
+				name: spec.Configuration{
+					Paths: map[string]spec.Responses{
+						path: map[string]spec.Response{
+							http.MethodGet: {
+								SequenceMode: mode,
+								Sequence: []spec.Response{
+									{StatusCode: http.StatusServiceUnavailable},
+									{StatusCode: http.StatusServiceUnavailable},
+									{StatusCode: http.StatusOK, Body: "finally"},
+								},
+							},
+						},
+					},
+				},
+			}
+		}
+
+		It("Sticks on the last response by default", func() {
+			Expect(app.AddConfig(sequenceConfig("sequenceLast", "/sequence/last", ""))).ShouldNot(HaveOccurred())
+
+			Expect(statusesFor("/sequence/last", 5)).To(Equal([]int{
+				http.StatusServiceUnavailable,
+				http.StatusServiceUnavailable,
+				http.StatusOK,
+				http.StatusOK,
+				http.StatusOK,
+			}))
+		})
+
+		It("Loops back to the first response", func() {
+			Expect(app.AddConfig(sequenceConfig("sequenceLoop", "/sequence/loop", spec.SequenceModeLoop))).
+				ShouldNot(HaveOccurred())
+
+			Expect(statusesFor("/sequence/loop", 4)).To(Equal([]int{
+				http.StatusServiceUnavailable,
+				http.StatusServiceUnavailable,
+				http.StatusOK,
+				http.StatusServiceUnavailable,
+			}))
+		})
+
+		It("Responds with a 404 once exhausted", func() {
+			Expect(app.AddConfig(sequenceConfig("sequenceNotFound", "/sequence/404", spec.SequenceModeNotFound))).
+				ShouldNot(HaveOccurred())
+
+			Expect(statusesFor("/sequence/404", 4)).To(Equal([]int{
+				http.StatusServiceUnavailable,
+				http.StatusServiceUnavailable,
+				http.StatusOK,
+				http.StatusNotFound,
+			}))
+		})
+
+		It("Rejects an unknown mode", func() {
+			Expect(app.AddConfig(sequenceConfig("sequenceUnknown", "/sequence/unknown", "shuffle"))).
+				Should(HaveOccurred())
+		})
+	})
+
+	Context("With request matching", func() {
+		path := "/matching/login/:userID"
+
+		BeforeEach(func() {
+			present := true
+			err := app.AddConfig(spec.Configurations{
+				"matching": spec.Configuration{
+					Paths: map[string]spec.Responses{
+						path: map[string]spec.Response{
+							http.MethodPost: {
+								StatusCode: http.StatusOK,
+								Body:       "fallback",
+								Candidates: []spec.Response{
+									{
+										Match:      &spec.Match{Headers: map[string]spec.ValueMatch{"X-Tenant": {Equals: "acme"}}},
+										StatusCode: http.StatusAccepted,
+										Body:       "header",
+									},
+									{
+										Match:      &spec.Match{Query: map[string]spec.ValueMatch{"debug": {Present: &present}}},
+										StatusCode: http.StatusAccepted,
+										Body:       "query",
+									},
+									{
+										Match:      &spec.Match{JSON: map[string]spec.ValueMatch{"user.roles.0": {Regex: "^adm"}}},
+										StatusCode: http.StatusAccepted,
+										Body:       "json",
+									},
+									{
+										Match:      &spec.Match{Form: map[string]spec.ValueMatch{"password": {Equals: "hunter2"}}},
+										StatusCode: http.StatusAccepted,
+										Body:       "form",
+									},
+								},
+							},
+						},
+					},
+				},
+			})
+
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		respondsWith := func(req *http.Request, expectedStatus int, expectedBody string) {
+			res, err := client.Do(req)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(expectedStatus))
+
+			resBytes, err := ioutil.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(string(resBytes)).To(Equal(expectedBody))
+		}
+
+		newRequest := func(query, contentType, body string) *http.Request {
+			req, err := http.NewRequest(
+				http.MethodPost,
+				fmt.Sprintf("http://127.0.0.1:%d/matching/login/dave%s", port, query),
+				strings.NewReader(body),
+			)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			if contentType != "" {
+				req.Header.Set("Content-Type", contentType)
+			}
+
+			return req
+		}
+
+		It("Matches on headers", func() {
+			req := newRequest("", "", "")
+			req.Header.Set("X-Tenant", "acme")
+			respondsWith(req, http.StatusAccepted, "header")
+		})
+
+		It("Matches on query parameters", func() {
+			respondsWith(newRequest("?debug", "", ""), http.StatusAccepted, "query")
+		})
+
+		It("Matches on JSON body fields", func() {
+			respondsWith(
+				newRequest("", "application/json", `{"user": {"roles": ["admin"]}}`),
+				http.StatusAccepted,
+				"json")
+		})
+
+		It("Matches on form fields", func() {
+			respondsWith(
+				newRequest("", "application/x-www-form-urlencoded", "password=hunter2"),
+				http.StatusAccepted,
+				"form")
+		})
+
+		It("Falls back when nothing matches", func() {
+			respondsWith(
+				newRequest("", "application/json", `{"user": {"roles": ["viewer"]}}`),
+				http.StatusOK,
+				"fallback")
+		})
+	})
+
+	Context("With the request journal", func() {
+		route := "/journal/accounts/:userID/flag"
+
+		BeforeEach(func() {
+			err := app.AddConfig(spec.Configurations{
+				"journaled": spec.Configuration{
+					Paths: map[string]spec.Responses{
+						route: map[string]spec.Response{
+							http.MethodPost: {StatusCode: http.StatusAccepted},
+						},
+					},
+				},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			for _, userID := range []string{"kirk", "spock"} {
+				req, err := http.NewRequest(
+					http.MethodPost,
+					fmt.Sprintf("http://127.0.0.1:%d/journal/accounts/%s/flag", port, userID),
+					strings.NewReader(userID),
+				)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				res, err := client.Do(req)
+				Expect(err).ShouldNot(HaveOccurred())
+				res.Body.Close()
+			}
+		})
+
+		AfterEach(func() {
+			req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("http://127.0.0.1:%d/gnockconfig/journal", configPort), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			res, err := client.Do(req)
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusNoContent))
+		})
+
+		It("Lists what was served", func() {
+			res, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d/gnockconfig/journal?config=journaled&path=%s", configPort, route))
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			var entries []JournalEntry
+			Expect(json.NewDecoder(res.Body).Decode(&entries)).ShouldNot(HaveOccurred())
+
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].Method).To(Equal(http.MethodPost))
+			Expect(entries[0].Path).To(Equal("/journal/accounts/kirk/flag"))
+			Expect(entries[0].Params).To(Equal(map[string]string{"userID": "kirk"}))
+			Expect(entries[0].Body).To(Equal("kirk"))
+			Expect(entries[0].StatusCode).To(Equal(http.StatusAccepted))
+			Expect(entries[1].Body).To(Equal("spock"))
+		})
+
+		It("Verifies request counts", func() {
+			verify := func(verification string) (int, VerificationResult) {
+				res, err := client.Post(
+					fmt.Sprintf("http://127.0.0.1:%d/gnockconfig/verify", configPort),
+					"application/json",
+					strings.NewReader(verification))
+				Expect(err).ShouldNot(HaveOccurred())
+				defer res.Body.Close()
+
+				result := VerificationResult{}
+				Expect(json.NewDecoder(res.Body).Decode(&result)).ShouldNot(HaveOccurred())
+
+				return res.StatusCode, result
+			}
+
+			status, result := verify(fmt.Sprintf(`{"method": "POST", "path": "%s", "count": 2}`, route))
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(result).To(Equal(VerificationResult{Pass: true, Count: 2}))
+
+			status, result = verify(`{"method": "POST", "path": "/journal/accounts/kirk/flag", "atLeast": 2}`)
+			Expect(status).To(Equal(http.StatusExpectationFailed))
+			Expect(result).To(Equal(VerificationResult{Pass: false, Count: 1}))
+		})
+	})
+
+	Context("With an upstream to proxy to", func() {
+		var upstream *httptest.Server
+
+		BeforeEach(func() {
+			upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Upstream", "yes")
+				w.WriteHeader(http.StatusAccepted)
+				fmt.Fprintf(w, "upstream %s %s", r.Method, r.URL.Path)
+			}))
+
+			err := app.AddConfig(spec.Configurations{
+				"proxied": spec.Configuration{
+					Proxy: upstream.URL,
+					Paths: map[string]spec.Responses{
+						"/proxied/mocked": map[string]spec.Response{
+							http.MethodGet: {StatusCode: http.StatusOK, Body: "mocked"},
+						},
+					},
+				},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			// Without a config select header unmatched requests would go to its upstream
+			app.RemoveConfig("proxied")
+			upstream.Close()
+		})
+
+		It("Proxies and records what the config can't answer", func() {
+			for _, path := range []string{"/proxied/mocked", "/proxied/real"} {
+				req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d%s", port, path), nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				req.Header.Set(ConfigSelectHeader, "proxied")
+
+				res, err := client.Do(req)
+				Expect(err).ShouldNot(HaveOccurred())
+				res.Body.Close()
+			}
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://127.0.0.1:%d/proxied/mocked", port), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			req.Header.Set(ConfigSelectHeader, "proxied")
+
+			res, err := client.Do(req)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusAccepted))
+			Expect(res.Header.Get("X-Upstream")).To(Equal("yes"))
+
+			resBytes, err := ioutil.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(string(resBytes)).To(Equal("upstream POST /proxied/mocked"))
+
+			res, err = client.Get(fmt.Sprintf("http://127.0.0.1:%d/gnockconfig/recordings", configPort))
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			recordings := spec.Configurations{}
+			Expect(json.NewDecoder(res.Body).Decode(&recordings)).ShouldNot(HaveOccurred())
+
+			Expect(recordings["proxied"].Paths).To(HaveLen(2))
+			Expect(recordings["proxied"].Paths["/proxied/real"]["get"].Body).To(Equal("upstream GET /proxied/real"))
+			Expect(recordings["proxied"].Paths["/proxied/mocked"]["post"].StatusCode).To(Equal(http.StatusAccepted))
+			Expect(recordings["proxied"].Paths["/proxied/mocked"]).ShouldNot(HaveKey("get"))
+		})
+	})
+
+	Context("With a mocked route on the config base path", func() {
+		It("Serves the mock, not the config endpoints", func() {
+			err := app.AddConfig(spec.Configurations{
+				"ownsGnockConfig": spec.Configuration{
+					Paths: map[string]spec.Responses{
+						"/gnockconfig": map[string]spec.Response{
+							http.MethodGet: {StatusCode: http.StatusOK, Body: "mocked"},
+						},
+					},
+				},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			res, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d/gnockconfig", port))
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			resBytes, err := ioutil.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(string(resBytes)).To(Equal("mocked"))
+		})
+	})
+
+	Context("Managing a single configuration", func() {
+		configURL := fmt.Sprintf("http://127.0.0.1:%d/gnockconfig/crud", configPort)
+
+		send := func(method, url, body string) (int, string) {
+			req, err := http.NewRequest(method, url, strings.NewReader(body))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			res, err := client.Do(req)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			resBytes, err := ioutil.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			return res.StatusCode, string(resBytes)
+		}
+
+		mock := func(path string) (int, string) {
+			return send(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d%s", port, path), "")
+		}
+
+		It("Replaces, patches, reads and deletes it", func() {
+			status, _ := send(http.MethodPut, configURL, `
+paths:
+  /crud/first:
+    get:
+      statusCode: 200
+      body: first
+`)
+			Expect(status).To(Equal(http.StatusOK))
+
+			status, body := mock("/crud/first")
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(Equal("first"))
+
+			status, _ = send(http.MethodPut, configURL, `
+paths:
+  /crud/first:
+    get:
+      statusCode: 202
+      body: replaced
+`)
+			Expect(status).To(Equal(http.StatusOK))
+
+			status, body = mock("/crud/first")
+			Expect(status).To(Equal(http.StatusAccepted))
+			Expect(body).To(Equal("replaced"))
+
+			status, _ = send(http.MethodPatch, configURL, `{"paths": {"/crud/second": {"get": {"statusCode": 200, "body": "second"}}}}`)
+			Expect(status).To(Equal(http.StatusOK))
+
+			status, body = mock("/crud/second")
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(Equal("second"))
+
+			status, body = send(http.MethodGet, configURL, "")
+			Expect(status).To(Equal(http.StatusOK))
+
+			configuration := spec.Configuration{}
+			Expect(json.Unmarshal([]byte(body), &configuration)).ShouldNot(HaveOccurred())
+			Expect(configuration.Paths).To(HaveKey("/crud/first"))
+			Expect(configuration.Paths).To(HaveKey("/crud/second"))
+			Expect(configuration.Paths["/crud/first"]["get"].Body).To(Equal("replaced"))
+
+			status, _ = send(http.MethodDelete, configURL, "")
+			Expect(status).To(Equal(http.StatusNoContent))
+
+			status, _ = mock("/crud/first")
+			Expect(status).To(Equal(http.StatusNotFound))
+
+			status, _ = send(http.MethodGet, configURL, "")
+			Expect(status).To(Equal(http.StatusNotFound))
+		})
+
+		It("Hands a path over to the next config when its default is deleted", func() {
+			for _, name := range []string{"handoverFirst", "handoverSecond"} {
+				status, _ := send(
+					http.MethodPut,
+					fmt.Sprintf("http://127.0.0.1:%d/gnockconfig/%s", configPort, name),
+					fmt.Sprintf(`{"paths": {"/crud/handover": {"get": {"statusCode": 200, "body": "%s"}}}}`, name))
+				Expect(status).To(Equal(http.StatusOK))
+			}
+
+			_, body := mock("/crud/handover")
+			Expect(body).To(Equal("handoverFirst"))
+
+			status, _ := send(http.MethodDelete, fmt.Sprintf("http://127.0.0.1:%d/gnockconfig/handoverFirst", configPort), "")
+			Expect(status).To(Equal(http.StatusNoContent))
+
+			_, body = mock("/crud/handover")
+			Expect(body).To(Equal("handoverSecond"))
+		})
+
+		It("Keeps a replaced config the default for its paths", func() {
+			put := func(name, body string) {
+				status, _ := send(
+					http.MethodPut,
+					fmt.Sprintf("http://127.0.0.1:%d/gnockconfig/%s", configPort, name),
+					fmt.Sprintf(`{"paths": {"/crud/order": {"get": {"statusCode": 200, "body": "%s"}}}}`, body))
+				Expect(status).To(Equal(http.StatusOK))
+			}
+
+			put("orderFirst", "first")
+			put("orderSecond", "second")
+			put("orderFirst", "first again")
+
+			_, body := mock("/crud/order")
+			Expect(body).To(Equal("first again"))
+
+			for _, name := range []string{"orderFirst", "orderSecond"} {
+				status, _ := send(http.MethodDelete, fmt.Sprintf("http://127.0.0.1:%d/gnockconfig/%s", configPort, name), "")
+				Expect(status).To(Equal(http.StatusNoContent))
+			}
+		})
+
+		It("Patches a method however its case is written", func() {
+			status, _ := send(http.MethodPut, configURL, `{"paths": {"/crud/case": {"get": {"statusCode": 200, "body": "old"}}}}`)
+			Expect(status).To(Equal(http.StatusOK))
+
+			status, body := send(http.MethodPatch, configURL, `{"paths": {"/crud/case": {"GET": {"statusCode": 200, "body": "new"}}}}`)
+			Expect(status).To(Equal(http.StatusOK))
+
+			configuration := spec.Configuration{}
+			Expect(json.Unmarshal([]byte(body), &configuration)).ShouldNot(HaveOccurred())
+			Expect(configuration.Paths["/crud/case"]).To(HaveLen(1))
+
+			_, body = mock("/crud/case")
+			Expect(body).To(Equal("new"))
+
+			status, _ = send(http.MethodDelete, configURL, "")
+			Expect(status).To(Equal(http.StatusNoContent))
+		})
+
+		It("Removes everything on reset", func() {
+			status, _ := send(http.MethodPut, configURL, `{"paths": {"/crud/reset": {"get": {"statusCode": 200}}}}`)
+			Expect(status).To(Equal(http.StatusOK))
+
+			status, _ = send(http.MethodDelete, fmt.Sprintf("http://127.0.0.1:%d/gnockconfig", configPort), "")
+			Expect(status).To(Equal(http.StatusNoContent))
+
+			status, _ = mock("/crud/reset")
+			Expect(status).To(Equal(http.StatusNotFound))
+
+			_, body := send(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/gnockconfig", configPort), "")
+			Expect(body).To(MatchJSON("[]"))
+		})
+	})
+
+	// These are most useful run with the race detector, as `make test` does
+	Context("Concurrently", func() {
+		It("Adds, expires, removes and serves configs", func() {
+			var wg sync.WaitGroup
+
+			for i := 0; i < 10; i++ {
+				wg.Add(3)
+
+				go func(i int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					res, err := client.Post(
+						fmt.Sprintf("http://127.0.0.1:%d/gnockconfig", configPort),
+						"application/json",
+						strings.NewReader(fmt.Sprintf(
+							`{"concurrent%d": {"ttl": "50ms", "paths": {"/concurrent/:id": {"get": {"statusCode": 200}}}}}`,
+							i%3)))
+					Expect(err).ShouldNot(HaveOccurred())
+					res.Body.Close()
+
+					Expect(res.StatusCode).To(Equal(http.StatusCreated))
+				}(i)
+
+				go func(i int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					res, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d/concurrent/%d", port, i))
+					Expect(err).ShouldNot(HaveOccurred())
+					res.Body.Close()
+
+					Expect(res.StatusCode).To(BeElementOf(http.StatusOK, http.StatusNotFound))
+				}(i)
+
+				go func(i int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					app.RemoveConfig(fmt.Sprintf("concurrent%d", i%3))
+				}(i)
+			}
+
+			wg.Wait()
+
+			Eventually(func() []string {
+				return app.ConfigNames()
+			}).ShouldNot(ContainElement(HavePrefix("concurrent")))
+		})
+
+		It("Serves a route as soon as its config is added", func() {
+			err := app.AddConfig(spec.Configurations{
+				"immediate": spec.Configuration{
+					Paths: map[string]spec.Responses{
+						"/immediate": map[string]spec.Response{
+							http.MethodGet: {StatusCode: http.StatusOK},
+						},
+					},
+				},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			res, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d/immediate", port))
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		})
+	})
+
+	Context("With an invalid configuration", func() {
+		It("Rejects the whole document, listing every problem", func() {
+			res, err := client.Post(fmt.Sprintf("http://127.0.0.1:%d/gnockconfig", configPort), "application/x-yaml", strings.NewReader(`
+validFirst:
+  paths:
+    /invalid/valid:
+      get:
+        statusCode: 200
+invalidSecond:
+  ttl: forever
+  paths:
+    /invalid/one:
+      get:
+        statusCode: 200
+      fetch:
+        statusCode: 200
+    /invalid/two:
+      post:
+        statusCode: 1000
+        bodyTemplate: "{{.unclosed"
+        sequence:
+          - delay: soon
+`))
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+
+			var problems ValidationErrors
+			Expect(json.NewDecoder(res.Body).Decode(&problems)).ShouldNot(HaveOccurred())
+
+			type location struct{ config, path, method, field string }
+			var locations []location
+			for _, problem := range problems {
+				Expect(problem.Message).ShouldNot(BeEmpty())
+				locations = append(locations, location{problem.Config, problem.Path, problem.Method, problem.Field})
+			}
+
+			Expect(locations).To(Equal([]location{
+				{"invalidSecond", "", "", "ttl"},
+				{"invalidSecond", "/invalid/one", "FETCH", ""},
+				{"invalidSecond", "/invalid/two", "POST", "bodyTemplate"},
+				{"invalidSecond", "/invalid/two", "POST", "sequence[0].delay"},
+				{"invalidSecond", "/invalid/two", "POST", "statusCode"},
+			}))
+
+			_, ok := app.Config("validFirst")
+			Expect(ok).To(BeFalse())
+
+			res, err = client.Get(fmt.Sprintf("http://127.0.0.1:%d/invalid/valid", port))
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		It("Rejects a method given twice in different cases", func() {
+			problems := app.Validate(spec.Configurations{
+				"twice": spec.Configuration{
+					Paths: map[string]spec.Responses{
+						"/twice": {"get": {StatusCode: 200}, "GET": {StatusCode: 201}},
+					},
+				},
+			})
+
+			Expect(problems).To(HaveLen(1))
+			Expect(problems[0].Method).To(Equal(http.MethodGet))
+			Expect(problems[0].Message).To(Equal("given more than once, as GET and get"))
+		})
+	})
+
+	Context("With a config named after an admin endpoint", func() {
+		It("Rejects it", func() {
+			for _, name := range []string{"journal", "verify", "recordings", "tls"} {
+				res, err := client.Post(
+					fmt.Sprintf("http://127.0.0.1:%d/gnockconfig", configPort),
+					"application/json",
+					strings.NewReader(fmt.Sprintf(`{"%s": {"paths": {"/reserved": {"get": {"statusCode": 200}}}}}`, name)))
+				Expect(err).ShouldNot(HaveOccurred())
+				res.Body.Close()
+
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest), name)
+
+				_, ok := app.Config(name)
+				Expect(ok).To(BeFalse(), name)
+			}
+		})
+	})
+
+	Context("Decoding posted configs", func() {
+		post := func(query, document string) (int, string) {
+			res, err := client.Post(
+				fmt.Sprintf("http://127.0.0.1:%d/gnockconfig%s", configPort, query),
+				"application/x-yaml",
+				strings.NewReader(document))
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			resBytes, err := ioutil.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			return res.StatusCode, string(resBytes)
+		}
+
+		misspelled := `
+misspelled:
+  override: true
+  paths:
+    /decoding/misspelled:
+      get:
+        statuscode: 200
+        match:
+          headers:
+            X-Gnock:
+              equal: who
+  resources:
+    crew:
+      idfield: name
+`
+
+		It("Rejects unknown fields with their line", func() {
+			status, body := post("", misspelled)
+			Expect(status).To(Equal(http.StatusBadRequest))
+			Expect(body).To(ContainSubstring("line 3: field override not found in type spec.Configuration"))
+			Expect(body).To(ContainSubstring("line 7: field statuscode not found in type spec.Response"))
+			Expect(body).To(ContainSubstring("line 11: field equal not found in type spec.ValueMatch"))
+			Expect(body).To(ContainSubstring("line 14: field idfield not found in type spec.Resource"))
+		})
+
+		It("Accepts unknown fields when strict decoding is turned off", func() {
+			status, _ := post("?strict=false", misspelled)
+			Expect(status).To(Equal(http.StatusCreated))
+		})
+
+		It("Accepts headers and status as aliases", func() {
+			status, _ := post("", `
+aliased:
+  paths:
+    /decoding/aliased:
+      get:
+        status: 202
+        headers:
+          - X-Aliased: "yes"
+`)
+			Expect(status).To(Equal(http.StatusCreated))
+
+			res, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d/decoding/aliased", port))
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusAccepted))
+			Expect(res.Header.Get("X-Aliased")).To(Equal("yes"))
+		})
+
+		It("Accepts the examples", func() {
+			for _, example := range []string{"../examples/example.yaml", "../examples/example.json"} {
+				document, err := ioutil.ReadFile(example)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				status, body := post("", string(document))
+				Expect(status).To(Equal(http.StatusCreated), body)
+			}
+		})
+	})
+
+	Context("Syncing configs loaded from files", func() {
+		fileConfig := func(body string) spec.Configuration {
+			return spec.Configuration{
+				Paths: map[string]spec.Responses{
+					"/files/" + body: map[string]spec.Response{
+						http.MethodGet: {StatusCode: http.StatusOK, Body: body},
+					},
+				},
+			}
+		}
+
+		It("Only adds, replaces and removes what changed in the files", func() {
+			added, removed, err := app.SyncFileConfigs(spec.Configurations{
+				"fileKept":    fileConfig("kept"),
+				"fileChanged": fileConfig("before"),
+				"fileRemoved": fileConfig("removed"),
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(added).To(Equal([]string{"fileChanged", "fileKept", "fileRemoved"}))
+			Expect(removed).To(BeEmpty())
+
+			Expect(app.AddConfig(spec.Configurations{"filePosted": fileConfig("posted")})).ShouldNot(HaveOccurred())
+
+			added, removed, err = app.SyncFileConfigs(spec.Configurations{
+				"fileKept":    fileConfig("kept"),
+				"fileChanged": fileConfig("after"),
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(added).To(Equal([]string{"fileChanged"}))
+			Expect(removed).To(Equal([]string{"fileRemoved"}))
+
+			for _, name := range []string{"fileKept", "fileChanged", "filePosted"} {
+				_, ok := app.Config(name)
+				Expect(ok).To(BeTrue(), name)
+			}
+
+			changed, _ := app.Config("fileChanged")
+			Expect(changed.Paths).To(HaveKey("/files/after"))
+
+			_, ok := app.Config("fileRemoved")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("Served over mutual TLS", func() {
+		tlsPort := 1711
+		tlsConfigPort := 1712
+		var tlsApp *gnocker
+
+		// fasthttp waits on open connections when shutting down, so nothing here is kept alive
+		plain := http.Client{Timeout: 3 * time.Second, Transport: &http.Transport{DisableKeepAlives: true}}
+
+		BeforeEach(func() {
+			tlsConfig, authority, err := certs.Setup(certs.Settings{
+				Generate:   true,
+				Hosts:      []string{"127.0.0.1"},
+				ClientAuth: "verify",
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			tlsApp = New(
+				WithPort(tlsPort),
+				WithConfigPort(tlsConfigPort),
+				WithTLS(tlsConfig),
+				WithAuthority(authority))
+
+			go func() {
+				defer GinkgoRecover()
+				Expect(tlsApp.Start()).ShouldNot(HaveOccurred())
+			}()
+
+			Eventually(func() error {
+				res, err := plain.Get(fmt.Sprintf("http://127.0.0.1:%d/gnockconfig", tlsConfigPort))
+				if err == nil {
+					res.Body.Close()
+				}
+				return err
+			}).ShouldNot(HaveOccurred())
+
+			err = tlsApp.AddConfig(spec.Configurations{
+				"secure": spec.Configuration{
+					Paths: map[string]spec.Responses{
+						"/secure": map[string]spec.Response{
+							http.MethodGet: {StatusCode: http.StatusOK, Body: "secure"},
+						},
+					},
+				},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(tlsApp.Shutdown()).ShouldNot(HaveOccurred())
+		})
+
+		It("Serves clients trusting the CA with a certificate it issued", func() {
+			res, err := plain.Get(fmt.Sprintf("http://127.0.0.1:%d/gnockconfig/tls/ca.pem", tlsConfigPort))
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			caPEM, err := ioutil.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			roots := x509.NewCertPool()
+			Expect(roots.AppendCertsFromPEM(caPEM)).To(BeTrue())
+
+			res, err = plain.Post(fmt.Sprintf("http://127.0.0.1:%d/gnockconfig/tls/client-cert", tlsConfigPort), "", nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			issued := ClientCertificate{}
+			Expect(json.NewDecoder(res.Body).Decode(&issued)).ShouldNot(HaveOccurred())
+
+			clientCert, err := tls.X509KeyPair([]byte(issued.Cert), []byte(issued.Key))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			secureURL := fmt.Sprintf("https://127.0.0.1:%d/secure", tlsPort)
+
+			anonymous := http.Client{Transport: &http.Transport{DisableKeepAlives: true, TLSClientConfig: &tls.Config{RootCAs: roots}}}
+			_, err = anonymous.Get(secureURL)
+			Expect(err).Should(HaveOccurred())
+
+			mutual := http.Client{Transport: &http.Transport{DisableKeepAlives: true, TLSClientConfig: &tls.Config{
+				RootCAs:      roots,
+				Certificates: []tls.Certificate{clientCert},
+			}}}
+			res, err = mutual.Get(secureURL)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+			resBytes, err := ioutil.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(string(resBytes)).To(Equal("secure"))
+		})
+	})
+
+	Context("With configs on ports of their own", func() {
+		// Keeping connections alive would hide a port being closed
+		plain := http.Client{Timeout: 3 * time.Second, Transport: &http.Transport{DisableKeepAlives: true}}
+
+		get := func(p int) (string, error) {
+			res, err := plain.Get(fmt.Sprintf("http://127.0.0.1:%d/ports/health", p))
+			if err != nil {
+				return "", err
+			}
+			defer res.Body.Close()
+
+			resBytes, err := ioutil.ReadAll(res.Body)
+			return fmt.Sprintf("%d %s", res.StatusCode, resBytes), err
+		}
+
+		health := func(body string, p int) spec.Configuration {
+			return spec.Configuration{
+				Port: p,
+				Paths: map[string]spec.Responses{
+					"/ports/health": map[string]spec.Response{
+						http.MethodGet: {StatusCode: http.StatusOK, Body: body},
+					},
+				},
+			}
+		}
+
+		AfterEach(func() {
+			app.RemoveConfig("portUsers")
+			app.RemoveConfig("portBilling")
+		})
+
+		It("Serves each on its port until it's removed", func() {
+			Expect(app.AddConfig(spec.Configurations{
+				"portUsers":   health("users", 1721),
+				"portBilling": health("billing", 1722),
+			})).ShouldNot(HaveOccurred())
+
+			Expect(get(1721)).To(Equal("200 users"))
+			Expect(get(1722)).To(Equal("200 billing"))
+			Expect(get(port)).To(HavePrefix("404"))
+
+			Expect(app.RemoveConfig("portUsers")).To(BeTrue())
+
+			_, err := get(1721)
+			Expect(err).Should(HaveOccurred())
+			Expect(get(1722)).To(Equal("200 billing"))
+		})
+
+		It("Moves a replaced config to its new port", func() {
+			Expect(app.AddConfig(spec.Configurations{"portUsers": health("users", 1721)})).ShouldNot(HaveOccurred())
+			Expect(app.AddConfig(spec.Configurations{"portUsers": health("moved", 1723)})).ShouldNot(HaveOccurred())
+
+			_, err := get(1721)
+			Expect(err).Should(HaveOccurred())
+			Expect(get(1723)).To(Equal("200 moved"))
+		})
+
+		It("Adds nothing when a port can't be listened on", func() {
+			taken, err := net.Listen("tcp", "127.0.0.1:1724")
+			Expect(err).ShouldNot(HaveOccurred())
+			defer taken.Close()
+
+			err = app.AddConfig(spec.Configurations{
+				"portUsers":   health("users", 1721),
+				"portBilling": health("billing", 1724),
+			})
+			Expect(err).Should(HaveOccurred())
+
+			_, ok := app.Config("portUsers")
+			Expect(ok).To(BeFalse())
+
+			_, err = get(1721)
+			Expect(err).Should(HaveOccurred())
+		})
+
+		It("Proxies what it can't answer to the upstream of the config on the port", func() {
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, "upstream %s", r.URL.Path)
+			}))
+			defer upstream.Close()
+
+			users := health("users", 1721)
+			users.Proxy = upstream.URL
+			Expect(app.AddConfig(spec.Configurations{"portUsers": users})).ShouldNot(HaveOccurred())
+
+			res, err := plain.Get("http://127.0.0.1:1721/ports/unknown")
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			resBytes, err := ioutil.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(string(resBytes)).To(Equal("upstream /ports/unknown"))
+
+			// The config isn't served on the main port, neither is its upstream
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/ports/unknown", port), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			req.Header.Set(ConfigSelectHeader, "portUsers")
+
+			res, err = plain.Do(req)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		It("Rejects the server's own ports", func() {
+			err := app.AddConfig(spec.Configurations{"portUsers": health("users", configPort)})
+
+			var problems ValidationErrors
+			Expect(errors.As(err, &problems)).To(BeTrue())
+			Expect(problems).To(HaveLen(1))
+			Expect(problems[0].Field).To(Equal("port"))
+		})
+	})
+
+	Context("With cookies", func() {
+		BeforeEach(func() {
+			err := app.AddConfig(spec.Configurations{
+				"cookies": spec.Configuration{
+					Paths: map[string]spec.Responses{
+						"/cookies/login": map[string]spec.Response{
+							http.MethodPost: {
+								StatusCode: http.StatusNoContent,
+								Cookies: []spec.Cookie{{
+									Name:     "session",
+									Value:    "abc123",
+									Path:     "/cookies",
+									Expires:  "1h",
+									HTTPOnly: true,
+									SameSite: "strict",
+								}},
+							},
+						},
+						"/cookies/me": map[string]spec.Response{
+							http.MethodGet: {
+								StatusCode: http.StatusUnauthorized,
+								Candidates: []spec.Response{{
+									Match:        &spec.Match{Cookies: map[string]spec.ValueMatch{"session": {Regex: "^abc"}}},
+									StatusCode:   http.StatusOK,
+									BodyTemplate: "session {{.Cookies.session}}",
+								}},
+							},
+						},
+					},
+				},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			app.RemoveConfig("cookies")
+		})
+
+		It("Sets them and matches on them", func() {
+			res, err := client.Post(fmt.Sprintf("http://127.0.0.1:%d/cookies/login", port), "", nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+
+			Expect(res.Cookies()).To(HaveLen(1))
+			cookie := res.Cookies()[0]
+			Expect(cookie.Name).To(Equal("session"))
+			Expect(cookie.Value).To(Equal("abc123"))
+			Expect(cookie.Path).To(Equal("/cookies"))
+			Expect(cookie.HttpOnly).To(BeTrue())
+			Expect(cookie.SameSite).To(Equal(http.SameSiteStrictMode))
+			Expect(cookie.Expires).To(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/cookies/me", port), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			res, err = client.Do(req)
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+			Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+
+			req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+			res, err = client.Do(req)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+			resBytes, err := ioutil.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(string(resBytes)).To(Equal("session abc123"))
+		})
+	})
+
+	Context("With ETags and Last-Modified", func() {
+		lastModified := time.Date(2020, 7, 17, 12, 0, 0, 0, time.UTC)
+
+		BeforeEach(func() {
+			err := app.AddConfig(spec.Configurations{
+				"conditional": spec.Configuration{
+					Paths: map[string]spec.Responses{
+						"/conditional/fixed": map[string]spec.Response{
+							http.MethodGet: {
+								StatusCode:   http.StatusOK,
+								Body:         "fixed",
+								ETag:         "v1",
+								LastModified: lastModified.Format(time.RFC3339),
+							},
+							http.MethodPut: {StatusCode: http.StatusOK, ETag: "v1"},
+						},
+						"/conditional/auto": map[string]spec.Response{
+							http.MethodGet: {StatusCode: http.StatusOK, Body: "auto", ETag: spec.ETagAuto},
+						},
+					},
+				},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			app.RemoveConfig("conditional")
+		})
+
+		request := func(method, path string, headers map[string]string) *http.Response {
+			req, err := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:%d%s", port, path), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			for header, value := range headers {
+				req.Header.Set(header, value)
+			}
+
+			res, err := client.Do(req)
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+
+			return res
+		}
+
+		It("Answers conditional requests", func() {
+			res := request(http.MethodGet, "/conditional/fixed", nil)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(res.Header.Get("ETag")).To(Equal(`"v1"`))
+			Expect(res.Header.Get("Last-Modified")).To(Equal(lastModified.Format(http.TimeFormat)))
+
+			res = request(http.MethodGet, "/conditional/fixed", map[string]string{"If-None-Match": `"v0", "v1"`})
+			Expect(res.StatusCode).To(Equal(http.StatusNotModified))
+			Expect(res.Header.Get("ETag")).To(Equal(`"v1"`))
+
+			res = request(http.MethodGet, "/conditional/fixed", map[string]string{"If-None-Match": `"v0"`})
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+			res = request(http.MethodGet, "/conditional/fixed", map[string]string{
+				"If-Modified-Since": lastModified.Add(time.Hour).Format(http.TimeFormat),
+			})
+			Expect(res.StatusCode).To(Equal(http.StatusNotModified))
+
+			res = request(http.MethodGet, "/conditional/fixed", map[string]string{
+				"If-Modified-Since": lastModified.Add(-time.Hour).Format(http.TimeFormat),
+			})
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+			res = request(http.MethodPut, "/conditional/fixed", map[string]string{"If-Match": `"v0"`})
+			Expect(res.StatusCode).To(Equal(http.StatusPreconditionFailed))
+
+			res = request(http.MethodPut, "/conditional/fixed", map[string]string{"If-Match": `"v1"`})
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("Computes ETags from the body", func() {
+			res := request(http.MethodGet, "/conditional/auto", nil)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+			etag := res.Header.Get("ETag")
+			Expect(etag).To(MatchRegexp(`^"[0-9a-f]{40}"$`))
+
+			res = request(http.MethodGet, "/conditional/auto", map[string]string{"If-None-Match": etag})
+			Expect(res.StatusCode).To(Equal(http.StatusNotModified))
+		})
+	})
+
+	Context("With template functions", func() {
+		seeded := func() spec.Configurations {
+			return spec.Configurations{
+				"funcs": spec.Configuration{
+					Seed: 1701,
+					Paths: map[string]spec.Responses{
+						"/funcs/random": map[string]spec.Response{
+							http.MethodGet: {
+								StatusCode:   http.StatusOK,
+								BodyTemplate: "{{uuid}} {{randInt 1 100}} {{randString 8}} {{fakeName}} {{fakeEmail}} {{fakeAddress}}",
+							},
+						},
+						"/funcs/:n": map[string]spec.Response{
+							http.MethodGet: {
+								StatusCode:   http.StatusOK,
+								BodyTemplate: `{{add .n 2}} {{mul .n 1.5}} {{div .n 4}} {{base64 "gnock"}} {{sha256 "gnock"}} {{now "2006"}}`,
+							},
+						},
+					},
+				},
+			}
+		}
+
+		get := func(path string) string {
+			res, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d%s", port, path))
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+			resBytes, err := ioutil.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			return string(resBytes)
+		}
+
+		AfterEach(func() {
+			app.RemoveConfig("funcs")
+		})
+
+		It("Generates the same values for the same seed", func() {
+			Expect(app.AddConfig(seeded())).ShouldNot(HaveOccurred())
+
+			first := get("/funcs/random")
+			Expect(first).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12} \d+ \w{8} .+ \S+@example\.\w+ \d+ .+, \w+$`))
+			Expect(get("/funcs/random")).ToNot(Equal(first))
+
+			Expect(app.AddConfig(seeded())).ShouldNot(HaveOccurred())
+			Expect(get("/funcs/random")).To(Equal(first))
+		})
+
+		It("Computes values", func() {
+			Expect(app.AddConfig(seeded())).ShouldNot(HaveOccurred())
+
+			Expect(get("/funcs/6")).To(Equal(fmt.Sprintf(
+				"8 9 1.5 Z25vY2s= 4efebc8cf2ced1f2da62fa2b1a25ea74ba02545d49d92b9c416f56978e855025 %d", time.Now().Year())))
+		})
+	})
+
+	Context("With templates for different content types", func() {
+		BeforeEach(func() {
+			err := app.AddConfig(spec.Configurations{
+				"engines": spec.Configuration{
+					Paths: map[string]spec.Responses{
+						"/engines/json": map[string]spec.Response{
+							http.MethodGet: {
+								StatusCode:   http.StatusOK,
+								Headers:      []map[string]string{{"Content-Type": "application/json"}},
+								BodyTemplate: `{"q": "{{jsonEscape .Query.q}}", "raw": {{toJSON .Query}}}`,
+							},
+						},
+						"/engines/html": map[string]spec.Response{
+							http.MethodGet: {
+								StatusCode:   http.StatusOK,
+								Headers:      []map[string]string{{"content-type": "text/html; charset=utf-8"}},
+								BodyTemplate: `<p>{{.Query.q}}</p>`,
+							},
+						},
+						"/engines/explicit": map[string]spec.Response{
+							http.MethodGet: {
+								StatusCode:     http.StatusOK,
+								TemplateEngine: spec.TemplateEngineHTML,
+								BodyTemplate:   `<p>{{.Query.q}}</p>`,
+							},
+						},
+					},
+				},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			app.RemoveConfig("engines")
+		})
+
+		get := func(path string) string {
+			res, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d%s?q=%s", port, path, url.QueryEscape(`a"b&c`)))
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			resBytes, err := ioutil.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			return string(resBytes)
+		}
+
+		It("Leaves JSON unescaped", func() {
+			Expect(get("/engines/json")).To(MatchJSON(`{"q": "a\"b&c", "raw": {"q": "a\"b&c"}}`))
+		})
+
+		It("Escapes HTML", func() {
+			Expect(get("/engines/html")).To(Equal("<p>a&#34;b&amp;c</p>"))
+			Expect(get("/engines/explicit")).To(Equal("<p>a&#34;b&amp;c</p>"))
+		})
+	})
+
+	Context("With a templated status code and headers", func() {
+		BeforeEach(func() {
+			err := app.AddConfig(spec.Configurations{
+				"templatedStatus": spec.Configuration{
+					Paths: map[string]spec.Responses{
+						"/templatedStatus/:userID": map[string]spec.Response{
+							http.MethodPost: {
+								StatusCodeTemplate: `{{if eq .userID "taken"}}409{{else}}201{{end}}`,
+								Headers:            []map[string]string{{"Location": "/v1/accounts/{{.userID}}", "X-Static": "{static}"}},
+							},
+						},
+						"/templatedStatus/broken/:code": map[string]spec.Response{
+							http.MethodPost: {StatusCodeTemplate: "{{.code}}"},
+						},
+					},
+				},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			app.RemoveConfig("templatedStatus")
+		})
+
+		post := func(path string) *http.Response {
+			res, err := client.Post(fmt.Sprintf("http://127.0.0.1:%d%s", port, path), "", nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+
+			return res
+		}
+
+		It("Renders them from the request", func() {
+			res := post("/templatedStatus/picard")
+			Expect(res.StatusCode).To(Equal(http.StatusCreated))
+			Expect(res.Header.Get("Location")).To(Equal("/v1/accounts/picard"))
+			Expect(res.Header.Get("X-Static")).To(Equal("{static}"))
+
+			res = post("/templatedStatus/taken")
+			Expect(res.StatusCode).To(Equal(http.StatusConflict))
+			Expect(res.Header.Get("Location")).To(Equal("/v1/accounts/taken"))
+		})
+
+		It("Fails when the status code isn't one", func() {
+			Expect(post("/templatedStatus/broken/418").StatusCode).To(Equal(http.StatusTeapot))
+			Expect(post("/templatedStatus/broken/tea").StatusCode).To(Equal(http.StatusInternalServerError))
+		})
+	})
+
+	Context("With a scenario", func() {
+		stateURL := fmt.Sprintf("http://127.0.0.1:%d/gnockconfig/approval/state", configPort)
+
+		BeforeEach(func() {
+			err := app.AddConfig(spec.Configurations{
+				"approval": spec.Configuration{
+					States: []string{"none", "pending", "approved"},
+					Paths: map[string]spec.Responses{
+						"/approval/requests": map[string]spec.Response{
+							http.MethodPost: {StatusCode: http.StatusCreated, State: "none", NextState: "pending"},
+						},
+						"/approval/requests/1": map[string]spec.Response{
+							http.MethodGet: {
+								StatusCode: http.StatusNotFound,
+								Candidates: []spec.Response{
+									{State: "pending", StatusCode: http.StatusOK, Body: "pending"},
+									{State: "approved", StatusCode: http.StatusOK, Body: "approved"},
+								},
+							},
+						},
+					},
+				},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			app.RemoveConfig("approval")
+		})
+
+		send := func(method, url, body string) (int, string) {
+			req, err := http.NewRequest(method, url, strings.NewReader(body))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			res, err := client.Do(req)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			resBytes, err := ioutil.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			return res.StatusCode, string(resBytes)
+		}
+
+		requests := fmt.Sprintf("http://127.0.0.1:%d/approval/requests", port)
+
+		It("Serves responses eligible in the current state and moves it along", func() {
+			_, state := send(http.MethodGet, stateURL, "")
+			Expect(state).To(MatchJSON(`{"state": "none"}`))
+
+			status, _ := send(http.MethodGet, requests+"/1", "")
+			Expect(status).To(Equal(http.StatusNotFound))
+
+			status, _ = send(http.MethodPost, requests, "")
+			Expect(status).To(Equal(http.StatusCreated))
+
+			status, body := send(http.MethodGet, requests+"/1", "")
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(Equal("pending"))
+
+			// Only one request at a time
+			status, _ = send(http.MethodPost, requests, "")
+			Expect(status).To(Equal(http.StatusNotFound))
+
+			status, state = send(http.MethodPut, stateURL, `{"state": "approved"}`)
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(state).To(MatchJSON(`{"state": "approved"}`))
+
+			_, body = send(http.MethodGet, requests+"/1", "")
+			Expect(body).To(Equal("approved"))
+
+			status, state = send(http.MethodDelete, stateURL, "")
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(state).To(MatchJSON(`{"state": "none"}`))
+
+			status, _ = send(http.MethodPost, requests, "")
+			Expect(status).To(Equal(http.StatusCreated))
+		})
+
+		It("Refuses states that weren't declared", func() {
+			status, _ := send(http.MethodPut, stateURL, `{"state": "rejected"}`)
+			Expect(status).To(Equal(http.StatusBadRequest))
+
+			status, _ = send(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/gnockconfig/nope/state", configPort), "")
+			Expect(status).To(Equal(http.StatusNotFound))
+
+			err := app.AddConfig(spec.Configurations{
+				"approval": spec.Configuration{
+					States: []string{"none"},
+					Paths: map[string]spec.Responses{
+						"/approval/requests": map[string]spec.Response{
+							http.MethodPost: {State: "none", NextState: "pending"},
+						},
+					},
+				},
+			})
+
+			var problems ValidationErrors
+			Expect(errors.As(err, &problems)).To(BeTrue())
+			Expect(problems).To(HaveLen(1))
+			Expect(problems[0].Field).To(Equal("nextState"))
+		})
+	})
+
+	Context("With a resource", func() {
+		BeforeEach(func() {
+			res, err := client.Post(fmt.Sprintf("http://127.0.0.1:%d/gnockconfig", configPort), "application/x-yaml", strings.NewReader(`
+resources:
+  resources:
+    accounts:
+      path: /resources/accounts
+      data:
+        - id: 1
+          name: picard
+          rank: captain
+          ship: {name: Enterprise, registry: NCC-1701-D}
+        - id: 2
+          name: riker
+          rank: commander
+        - id: 3
+          name: data
+          rank: commander
+  paths:
+    /resources/accounts/:id:
+      delete:
+        statusCode: 403
+`))
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+			Expect(res.StatusCode).To(Equal(http.StatusCreated))
+		})
+
+		AfterEach(func() {
+			app.RemoveConfig("resources")
+		})
+
+		send := func(method, path, body string) (*http.Response, string) {
+			req, err := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:%d/resources/accounts%s", port, path), strings.NewReader(body))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			res, err := client.Do(req)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			resBytes, err := ioutil.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			return res, string(resBytes)
+		}
+
+		It("Lists, filters and pages through items", func() {
+			res, body := send(http.MethodGet, "", "")
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(res.Header.Get("X-Total-Count")).To(Equal("3"))
+			Expect(body).To(ContainSubstring(`"ship":{"name":"Enterprise","registry":"NCC-1701-D"}`))
+
+			res, body = send(http.MethodGet, "?rank=commander&offset=1&limit=5", "")
+			Expect(res.Header.Get("X-Total-Count")).To(Equal("2"))
+			Expect(body).To(MatchJSON(`[{"id": 3, "name": "data", "rank": "commander"}]`))
+
+			res, body = send(http.MethodGet, "?limit=many", "")
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(body).To(Equal("limit must be zero or a positive number"))
+
+			res, body = send(http.MethodGet, "?rank=commander&limit=-5&offset=1", "")
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(body).To(Equal("limit must be zero or a positive number"))
+
+			res, body = send(http.MethodGet, "?offset=-1&rank=commander", "")
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(body).To(Equal("offset must be zero or a positive number"))
+		})
+
+		It("Creates, reads, replaces and patches items", func() {
+			res, body := send(http.MethodPost, "", `{"name": "worf", "rank": "lieutenant"}`)
+			Expect(res.StatusCode).To(Equal(http.StatusCreated))
+			Expect(res.Header.Get("Location")).To(Equal("/resources/accounts/4"))
+			Expect(body).To(MatchJSON(`{"id": 4, "name": "worf", "rank": "lieutenant"}`))
+
+			res, _ = send(http.MethodPost, "", `{"id": 4, "name": "worf"}`)
+			Expect(res.StatusCode).To(Equal(http.StatusConflict))
+
+			res, _ = send(http.MethodPost, "", `[]`)
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+
+			_, body = send(http.MethodGet, "/4", "")
+			Expect(body).To(MatchJSON(`{"id": 4, "name": "worf", "rank": "lieutenant"}`))
+
+			_, body = send(http.MethodPatch, "/4", `{"rank": "lieutenant commander"}`)
+			Expect(body).To(MatchJSON(`{"id": 4, "name": "worf", "rank": "lieutenant commander"}`))
+
+			_, body = send(http.MethodPut, "/4", `{"id": 9, "name": "worf"}`)
+			Expect(body).To(MatchJSON(`{"id": 4, "name": "worf"}`))
+
+			res, _ = send(http.MethodGet, "/9", "")
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+
+			res, _ = send(http.MethodPut, "/9", `{}`)
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		It("Lets paths override its routes", func() {
+			res, _ := send(http.MethodDelete, "/1", "")
+			Expect(res.StatusCode).To(Equal(http.StatusForbidden))
+
+			res, _ = send(http.MethodGet, "/1", "")
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		})
+	})
+
+	Context("With faults", func() {
+		// A fresh connection for each request, so each fault is seen by the request that caused it
+		impatient := http.Client{Timeout: 500 * time.Millisecond, Transport: &http.Transport{DisableKeepAlives: true}}
+
+		BeforeEach(func() {
+			paths := map[string]spec.Responses{}
+			for _, fault := range []string{
+				spec.FaultReset,
+				spec.FaultClose,
+				spec.FaultHeadersThenHang,
+				spec.FaultTruncatedBody,
+				spec.FaultMalformed,
+				spec.FaultHang,
+			} {
+				paths["/faults/"+fault] = map[string]spec.Response{
+					http.MethodGet: {StatusCode: http.StatusOK, Body: "a body long enough to cut short", Fault: fault},
+				}
+			}
+
+			Expect(app.AddConfig(spec.Configurations{"faults": spec.Configuration{Paths: paths}})).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			app.RemoveConfig("faults")
+		})
+
+		get := func(fault string) (*http.Response, error) {
+			return impatient.Get(fmt.Sprintf("http://127.0.0.1:%d/faults/%s", port, fault))
+		}
+
+		It("Breaks the connection without responding", func() {
+			for _, fault := range []string{spec.FaultReset, spec.FaultClose, spec.FaultMalformed, spec.FaultHang} {
+				_, err := get(fault)
+				Expect(err).Should(HaveOccurred(), fault)
+			}
+		})
+
+		It("Resets the connection", func() {
+			_, err := get(spec.FaultReset)
+			Expect(err).To(MatchError(ContainSubstring("connection reset")))
+		})
+
+		It("Breaks off partway through the response", func() {
+			for _, fault := range []string{spec.FaultHeadersThenHang, spec.FaultTruncatedBody} {
+				res, err := get(fault)
+				Expect(err).ShouldNot(HaveOccurred(), fault)
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+				Expect(res.ContentLength).To(Equal(int64(len("a body long enough to cut short"))))
+
+				_, err = ioutil.ReadAll(res.Body)
+				res.Body.Close()
+				Expect(err).Should(HaveOccurred(), fault)
+			}
+		})
+	})
+
+	Context("With latency and failures", func() {
+		flaky := func() spec.Configurations {
+			return spec.Configurations{
+				"flaky": spec.Configuration{
+					Seed: 1701,
+					Paths: map[string]spec.Responses{
+						"/flaky": map[string]spec.Response{
+							http.MethodGet: {
+								StatusCode:  http.StatusOK,
+								FailureRate: 0.5,
+								Failure:     &spec.Response{StatusCode: http.StatusServiceUnavailable, Body: "try again"},
+							},
+						},
+						"/slow": map[string]spec.Response{
+							http.MethodGet: {
+								StatusCode: http.StatusOK,
+								Latency:    &spec.Latency{Distribution: spec.LatencyUniform, Min: "20ms", Max: "40ms"},
+							},
+						},
+						"/random": map[string]spec.Response{
+							http.MethodGet: {StatusCode: http.StatusOK, BodyTemplate: "{{randInt 1 100}}"},
+						},
+					},
+				},
+			}
+		}
+
+		get := func(path string) int {
+			res, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d%s", port, path))
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+
+			return res.StatusCode
+		}
+
+		AfterEach(func() {
+			app.RemoveConfig("flaky")
+		})
+
+		It("Fails as often, and in the same order, for the same seed", func() {
+			statuses := func(others ...string) []int {
+				Expect(app.AddConfig(flaky())).ShouldNot(HaveOccurred())
+
+				var statuses []int
+				for i := 0; i < 20; i++ {
+					statuses = append(statuses, get("/flaky"))
+
+					// Whatever else the config serves doesn't change how the response fails
+					for _, other := range others {
+						get(other)
+					}
+				}
+
+				return statuses
+			}
+
+			first := statuses()
+			Expect(first).To(ContainElement(http.StatusOK))
+			Expect(first).To(ContainElement(http.StatusServiceUnavailable))
+			Expect(statuses()).To(Equal(first))
+			Expect(statuses("/slow", "/random")).To(Equal(first))
+		})
+
+		It("Only fails requests the response matches", func() {
+			config := flaky()
+			config["flaky"].Paths["/flaky"][http.MethodGet] = spec.Response{
+				StatusCode:  http.StatusOK,
+				FailureRate: 1,
+				Match:       &spec.Match{Headers: map[string]spec.ValueMatch{"X-Shields": {Equals: "down"}}},
+			}
+			Expect(app.AddConfig(config)).ShouldNot(HaveOccurred())
+
+			Expect(get("/flaky")).To(Equal(http.StatusNotFound))
+
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/flaky", port), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			req.Header.Set("X-Shields", "down")
+
+			res, err := client.Do(req)
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+			Expect(res.StatusCode).To(Equal(http.StatusInternalServerError))
+		})
+
+		It("Waits a latency drawn from the distribution", func() {
+			Expect(app.AddConfig(flaky())).ShouldNot(HaveOccurred())
+
+			for i := 0; i < 5; i++ {
+				start := time.Now()
+				Expect(get("/slow")).To(Equal(http.StatusOK))
+				Expect(time.Since(start)).To(BeNumerically(">=", 20*time.Millisecond))
+				Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+			}
+		})
+
+		It("Rejects a latency it can't draw from", func() {
+			config := flaky()
+			config["flaky"].Paths["/slow"][http.MethodGet] = spec.Response{
+				Latency: &spec.Latency{Distribution: spec.LatencyUniform, Min: "40ms", Max: "20ms"},
+			}
+
+			Expect(app.AddConfig(config)).Should(HaveOccurred())
+		})
+	})
+
+	Context("With a throttled body", func() {
+		BeforeEach(func() {
+			Expect(app.AddConfig(spec.Configurations{
+				"throttled": spec.Configuration{
+					Paths: map[string]spec.Responses{
+						"/throttled/drip": map[string]spec.Response{
+							http.MethodGet: {
+								StatusCode: http.StatusOK,
+								Body:       "0123456789",
+								Throttle:   &spec.Throttle{ChunkSize: 2, ChunkDelay: "30ms"},
+							},
+						},
+						"/throttled/rate": map[string]spec.Response{
+							http.MethodGet: {
+								StatusCode: http.StatusOK,
+								Body:       strings.Repeat("gnock", 20),
+								Throttle:   &spec.Throttle{BytesPerSecond: 500},
+							},
+						},
+					},
+				},
+			})).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			app.RemoveConfig("throttled")
+		})
+
+		// get times how long the headers and the whole body take to arrive
+		get := func(path string) (string, time.Duration, time.Duration) {
+			start := time.Now()
+			res, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d%s", port, path))
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+			headers := time.Since(start)
+
+			resBytes, err := ioutil.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			return string(resBytes), headers, time.Since(start)
+		}
+
+		It("Drips the body out a chunk at a time", func() {
+			body, headers, total := get("/throttled/drip")
+			Expect(body).To(Equal("0123456789"))
+			Expect(headers).To(BeNumerically("<", 30*time.Millisecond))
+			Expect(total).To(BeNumerically(">=", 4*30*time.Millisecond))
+		})
+
+		It("Sends the body at a rate", func() {
+			body, _, total := get("/throttled/rate")
+			Expect(body).To(Equal(strings.Repeat("gnock", 20)))
+			// 100 bytes at 500 a second, the first of the two 50 byte chunks going out right away
+			Expect(total).To(BeNumerically(">=", 100*time.Millisecond))
+		})
+	})
+
+	Context("With server-sent events", func() {
+		// The looping stream is abandoned partway, so its connection can't be reused
+		streaming := http.Client{Timeout: 3 * time.Second, Transport: &http.Transport{DisableKeepAlives: true}}
+
+		BeforeEach(func() {
+			Expect(app.AddConfig(spec.Configurations{
+				"events": spec.Configuration{
+					Paths: map[string]spec.Responses{
+						"/events/:who": map[string]spec.Response{
+							http.MethodGet: {
+								StatusCode: http.StatusOK,
+								Events: []spec.Event{
+									{Event: "greeting", ID: "1", Retry: 1000, Data: "gnock gnock\nwho's there?"},
+									{Data: "{{.who}}", Delay: "30ms"},
+								},
+							},
+						},
+						"/events/loop": map[string]spec.Response{
+							http.MethodGet: {
+								StatusCode: http.StatusOK,
+								Events:     []spec.Event{{Event: "tick", Data: "tock", Delay: "10ms"}},
+								LoopEvents: true,
+							},
+						},
+					},
+				},
+			})).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			app.RemoveConfig("events")
+		})
+
+		It("Streams the events", func() {
+			start := time.Now()
+			res, err := streaming.Get(fmt.Sprintf("http://127.0.0.1:%d/events/riker", port))
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(res.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+			Expect(res.Header.Get("Cache-Control")).To(Equal("no-cache"))
+
+			resBytes, err := ioutil.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically(">=", 30*time.Millisecond))
+
+			Expect(string(resBytes)).To(Equal(
+				"event: greeting\nid: 1\ndata: gnock gnock\ndata: who's there?\nretry: 1000\n\n" +
+					"data: riker\n\n"))
+		})
+
+		It("Loops the events until the client goes away", func() {
+			res, err := streaming.Get(fmt.Sprintf("http://127.0.0.1:%d/events/loop", port))
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			lines := bufio.NewScanner(res.Body)
+			ticks := 0
+			for ticks < 5 && lines.Scan() {
+				if lines.Text() == "data: tock" {
+					ticks++
+				}
+			}
+
+			Expect(ticks).To(Equal(5))
+		})
+
+		It("Rejects events with an ETag or Last-Modified", func() {
+			err := app.AddConfig(spec.Configurations{
+				"events": spec.Configuration{
+					Paths: map[string]spec.Responses{
+						"/events/loop": map[string]spec.Response{
+							http.MethodGet: {
+								Events:       []spec.Event{{Data: "tock", Delay: "10ms"}},
+								LoopEvents:   true,
+								ETag:         spec.ETagAuto,
+								LastModified: "2020-07-05T09:34:22Z",
+							},
+						},
+					},
+				},
+			})
+
+			var problems ValidationErrors
+			Expect(errors.As(err, &problems)).To(BeTrue())
+
+			var fields []string
+			for _, problem := range problems {
+				fields = append(fields, problem.Field)
+			}
+			Expect(fields).To(Equal([]string{"etag", "lastModified"}))
+		})
+
+		It("Rejects looping events without a delay", func() {
+			Expect(app.AddConfig(spec.Configurations{
+				"events": spec.Configuration{
+					Paths: map[string]spec.Responses{
+						"/events/loop": map[string]spec.Response{
+							http.MethodGet: {Events: []spec.Event{{Data: "tock"}}, LoopEvents: true},
+						},
+					},
+				},
+			})).Should(HaveOccurred())
+		})
+	})
+
+	Context("With a WebSocket", func() {
+		BeforeEach(func() {
+			Expect(app.AddConfig(spec.Configurations{
+				"chat": spec.Configuration{
+					Paths: map[string]spec.Responses{
+						"/chat/:room": map[string]spec.Response{
+							http.MethodGet: {
+								WebSocket: &spec.WebSocket{
+									OnConnect: []spec.WebSocketMessage{{Text: "welcome to {{.room}}"}},
+									Replies: []spec.WebSocketReply{
+										{Text: &spec.ValueMatch{Equals: "ping"}, Send: []spec.WebSocketMessage{{Text: "pong"}}},
+										{
+											JSON: map[string]spec.ValueMatch{"type": {Equals: "say"}},
+											Send: []spec.WebSocketMessage{{Text: `{"echo": "{{.MessageJSON.text}}"}`}},
+										},
+										{
+											Text:  &spec.ValueMatch{Regex: "^(bye|quit)$"},
+											Send:  []spec.WebSocketMessage{{Text: "{{.Message}} then"}},
+											Close: &spec.WebSocketClose{Code: 4000, Reason: "see you"},
+										},
+									},
+								},
+							},
+						},
+						"/notifications": map[string]spec.Response{
+							http.MethodGet: {
+								WebSocket: &spec.WebSocket{
+									Pushes: []spec.WebSocketPush{{Every: "10ms", Text: "red alert"}},
+									Close:  &spec.WebSocketClose{Code: websocket.CloseGoingAway, After: "100ms"},
+								},
+							},
+						},
+					},
+				},
+			})).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			app.RemoveConfig("chat")
+		})
+
+		dial := func(path string) *websocket.Conn {
+			conn, res, err := websocket.DefaultDialer.Dial(fmt.Sprintf("ws://127.0.0.1:%d%s", port, path), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(res.StatusCode).To(Equal(http.StatusSwitchingProtocols))
+			Expect(conn.SetReadDeadline(time.Now().Add(3 * time.Second))).ShouldNot(HaveOccurred())
+
+			return conn
+		}
+
+		read := func(conn *websocket.Conn) string {
+			_, message, err := conn.ReadMessage()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			return string(message)
+		}
+
+		It("Holds the scripted conversation", func() {
+			conn := dial("/chat/ten-forward")
+			defer conn.Close()
+
+			Expect(read(conn)).To(Equal("welcome to ten-forward"))
+
+			Expect(conn.WriteMessage(websocket.TextMessage, []byte("ping"))).ShouldNot(HaveOccurred())
+			Expect(read(conn)).To(Equal("pong"))
+
+			Expect(conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "say", "text": "engage"}`))).ShouldNot(HaveOccurred())
+			Expect(read(conn)).To(MatchJSON(`{"echo": "engage"}`))
+
+			Expect(conn.WriteMessage(websocket.TextMessage, []byte("bye"))).ShouldNot(HaveOccurred())
+			Expect(read(conn)).To(Equal("bye then"))
+
+			_, _, err := conn.ReadMessage()
+			Expect(websocket.IsCloseError(err, 4000)).To(BeTrue(), fmt.Sprint(err))
+			Expect(err.(*websocket.CloseError).Text).To(Equal("see you"))
+		})
+
+		It("Pushes messages until it closes", func() {
+			conn := dial("/notifications")
+			defer conn.Close()
+
+			pushes := 0
+			for {
+				_, message, err := conn.ReadMessage()
+				if err != nil {
+					Expect(websocket.IsCloseError(err, websocket.CloseGoingAway)).To(BeTrue(), fmt.Sprint(err))
+					break
+				}
+
+				Expect(string(message)).To(Equal("red alert"))
+				pushes++
+			}
+
+			Expect(pushes).To(BeNumerically(">=", 3))
+		})
+
+		It("Closes on text that isn't UTF-8", func() {
+			conn := dial("/chat/ten-forward")
+			defer conn.Close()
+
+			Expect(read(conn)).To(Equal("welcome to ten-forward"))
+			Expect(conn.WriteMessage(websocket.TextMessage, []byte{0xff, 0xfe})).ShouldNot(HaveOccurred())
+
+			_, _, err := conn.ReadMessage()
+			Expect(websocket.IsCloseError(err, websocket.CloseInvalidFramePayloadData)).To(BeTrue(), fmt.Sprint(err))
+		})
+
+		It("Asks for an upgrade", func() {
+			res, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d/notifications", port))
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusUpgradeRequired))
+		})
+	})
 })
@@ -0,0 +1,209 @@
+package gnocker
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber"
+	"github.com/zerbitx/gnockgnock/encode"
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+type (
+	// JournalEntry is a record of a single request served by a configured route
+	JournalEntry struct {
+		Time       time.Time         `json:"time"`
+		Config     string            `json:"config"`
+		Method     string            `json:"method"`
+		Path       string            `json:"path"`
+		Route      string            `json:"route"`
+		Params     map[string]string `json:"params"`
+		Headers    map[string]string `json:"headers"`
+		Body       string            `json:"body"`
+		StatusCode int               `json:"statusCode"`
+	}
+
+	// Verification asks how many journaled requests match the filter and checks that count against the constraints
+	Verification struct {
+		Config  string `json:"config" yaml:"config"`
+		Path    string `json:"path" yaml:"path"`
+		Method  string `json:"method" yaml:"method"`
+		Count   *int   `json:"count" yaml:"count"`
+		AtLeast *int   `json:"atLeast" yaml:"atLeast"`
+		AtMost  *int   `json:"atMost" yaml:"atMost"`
+	}
+
+	// VerificationResult reports the outcome of a Verification
+	VerificationResult struct {
+		Pass  bool `json:"pass"`
+		Count int  `json:"count"`
+	}
+
+	// journal is a bounded, in memory, log of served requests. Once full the oldest entries are dropped.
+	journal struct {
+		mu      sync.Mutex
+		entries []JournalEntry
+		size    int
+	}
+
+	journalFilter struct {
+		config string
+		path   string
+		method string
+	}
+)
+
+func newJournal(size int) *journal {
+	return &journal{size: size}
+}
+
+func (j *journal) record(entry JournalEntry) {
+	if j.size <= 0 {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if len(j.entries) >= j.size {
+		j.entries = append(j.entries[:0], j.entries[len(j.entries)-j.size+1:]...)
+	}
+
+	j.entries = append(j.entries, entry)
+}
+
+func (j *journal) find(filter journalFilter) []JournalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	found := []JournalEntry{}
+	for _, entry := range j.entries {
+		if filter.matches(entry) {
+			found = append(found, entry)
+		}
+	}
+
+	return found
+}
+
+func (j *journal) clear() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.entries = nil
+}
+
+// matches reports if an entry passes the filter, paths match either the request path or the configured route
+func (f journalFilter) matches(entry JournalEntry) bool {
+	if f.config != "" && f.config != entry.Config {
+		return false
+	}
+
+	if f.method != "" && !strings.EqualFold(f.method, entry.Method) {
+		return false
+	}
+
+	if f.path != "" && f.path != entry.Path && f.path != entry.Route {
+		return false
+	}
+
+	return true
+}
+
+func (v Verification) check(count int) bool {
+	if v.Count != nil && count != *v.Count {
+		return false
+	}
+
+	if v.AtLeast != nil && count < *v.AtLeast {
+		return false
+	}
+
+	if v.AtMost != nil && count > *v.AtMost {
+		return false
+	}
+
+	// With no constraints at all, at least one request is expected
+	if v.Count == nil && v.AtLeast == nil && v.AtMost == nil {
+		return count > 0
+	}
+
+	return true
+}
+
+// journalEntry captures what is known about a request once it has been served
+func journalEntry(c *fiber.Ctx, configName, route string) JournalEntry {
+	headers := map[string]string{}
+	c.Fasthttp.Request.Header.VisitAll(func(key, value []byte) {
+		if existing, ok := headers[string(key)]; ok {
+			headers[string(key)] = existing + ", " + string(value)
+			return
+		}
+		headers[string(key)] = string(value)
+	})
+
+	return JournalEntry{
+		Time:       time.Now(),
+		Config:     configName,
+		Method:     c.Method(),
+		Path:       c.Path(),
+		Route:      route,
+		Params:     routeParams(c),
+		Headers:    headers,
+		Body:       string(c.Fasthttp.Request.Body()),
+		StatusCode: c.Fasthttp.Response.StatusCode(),
+	}
+}
+
+func (g *gnocker) initJournalEndpoints() {
+	journalPath := g.configBasePath + "/journal"
+	verifyPath := g.configBasePath + "/verify"
+
+	g.logger.WithField("journal", journalPath).WithField("verify", verifyPath).Debug("journal endpoints")
+
+	g.admin.Get(journalPath, func(c *fiber.Ctx) {
+		entries := g.journal.find(journalFilter{
+			config: c.Query("config"),
+			path:   c.Query("path"),
+			method: c.Query("method"),
+		})
+
+		if err := encode.JSONIndented(entries, c.Fasthttp.Response.BodyWriter()); err != nil {
+			g.logger.WithError(err).Error("Failed to encode response")
+			c.SendStatus(http.StatusInternalServerError)
+		}
+	})
+
+	g.admin.Delete(journalPath, func(c *fiber.Ctx) {
+		g.journal.clear()
+		c.SendStatus(http.StatusNoContent)
+	})
+
+	g.admin.Post(verifyPath, func(c *fiber.Ctx) {
+		verification := Verification{}
+		if err := spec.Decode(strings.NewReader(c.Body()), &verification, g.strictFor(c)); err != nil {
+			g.logger.WithError(err).Error("failed to decode verification")
+			c.Send(err.Error())
+			c.SendStatus(http.StatusBadRequest)
+			return
+		}
+
+		count := len(g.journal.find(journalFilter{
+			config: verification.Config,
+			path:   verification.Path,
+			method: verification.Method,
+		}))
+
+		result := VerificationResult{Pass: verification.check(count), Count: count}
+		if !result.Pass {
+			c.Status(http.StatusExpectationFailed)
+		}
+
+		if err := encode.JSONIndented(result, c.Fasthttp.Response.BodyWriter()); err != nil {
+			g.logger.WithError(err).Error("Failed to encode response")
+			c.SendStatus(http.StatusInternalServerError)
+		}
+	})
+}
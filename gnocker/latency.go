@@ -0,0 +1,222 @@
+package gnocker
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber"
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+type (
+	// latency is how long to wait before each response
+	latency func() time.Duration
+
+	// percentile is the latency at a percentile, from 0 to 1
+	percentile struct {
+		at      float64
+		latency time.Duration
+	}
+
+	// percentileTable is sorted by percentile
+	percentileTable []percentile
+)
+
+// newLatency draws from the response's latency distribution with r, or waits its fixed delay when it has none
+func newLatency(options spec.Response, r *seededRand) (latency, error) {
+	if options.Latency == nil {
+		delay := options.DelayDuration
+		return func() time.Duration { return delay }, nil
+	}
+
+	if options.Delay != "" {
+		return nil, errors.New("a response has either a delay or a latency")
+	}
+
+	l := options.Latency
+
+	switch l.Distribution {
+	case spec.LatencyUniform:
+		min, max, err := latencyBounds(l.Min, l.Max)
+		if err != nil {
+			return nil, err
+		}
+
+		return func() time.Duration {
+			return min + time.Duration(r.float64()*float64(max-min))
+		}, nil
+	case spec.LatencyNormal, spec.LatencyLogNormal:
+		mean, stdDev, err := latencyMoments(l.Mean, l.StdDev)
+		if err != nil {
+			return nil, err
+		}
+
+		if l.Distribution == spec.LatencyNormal {
+			return func() time.Duration {
+				return time.Duration(math.Max(0, mean+stdDev*r.normFloat64()))
+			}, nil
+		}
+
+		// The parameters of the underlying normal distribution giving the mean and standard deviation asked for
+		sigma := math.Sqrt(math.Log(1 + stdDev*stdDev/(mean*mean)))
+		mu := math.Log(mean) - sigma*sigma/2
+
+		return func() time.Duration {
+			return time.Duration(math.Exp(mu + sigma*r.normFloat64()))
+		}, nil
+	case spec.LatencyPercentiles:
+		table, err := percentiles(l.Percentiles)
+		if err != nil {
+			return nil, err
+		}
+
+		return func() time.Duration {
+			return table.at(r.float64())
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown latency distribution %q", l.Distribution)
+	}
+}
+
+func latencyBounds(min, max string) (time.Duration, time.Duration, error) {
+	minDuration, err := requiredDuration("min", min)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	maxDuration, err := requiredDuration("max", max)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if maxDuration < minDuration {
+		return 0, 0, fmt.Errorf("max %s is less than min %s", max, min)
+	}
+
+	return minDuration, maxDuration, nil
+}
+
+func latencyMoments(mean, stdDev string) (float64, float64, error) {
+	meanDuration, err := requiredDuration("mean", mean)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	stdDevDuration, err := requiredDuration("stdDev", stdDev)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if meanDuration <= 0 {
+		return 0, 0, errors.New("mean has to be more than 0")
+	}
+
+	return float64(meanDuration), float64(stdDevDuration), nil
+}
+
+func requiredDuration(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, fmt.Errorf("%s is required", name)
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if d < 0 {
+		return 0, fmt.Errorf("%s can't be negative", name)
+	}
+
+	return d, nil
+}
+
+// percentiles sorts a percentile table, which starts from no latency at all unless it says otherwise at 0
+func percentiles(table map[string]string) (percentileTable, error) {
+	if len(table) == 0 {
+		return nil, errors.New("percentiles are required")
+	}
+
+	sorted := percentileTable{}
+	for at, value := range table {
+		p, err := strconv.ParseFloat(at, 64)
+		if err != nil || p < 0 || p > 100 {
+			return nil, fmt.Errorf("%s is not a percentile between 0 and 100", at)
+		}
+
+		d, err := requiredDuration(at, value)
+		if err != nil {
+			return nil, err
+		}
+
+		sorted = append(sorted, percentile{at: p / 100, latency: d})
+	}
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].at < sorted[j].at })
+
+	if sorted[0].at > 0 {
+		sorted = append(percentileTable{{}}, sorted...)
+	}
+
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].at == sorted[i-1].at {
+			return nil, fmt.Errorf("percentile %g is given more than once", sorted[i].at*100)
+		}
+
+		if sorted[i].latency < sorted[i-1].latency {
+			return nil, fmt.Errorf("the latency at percentile %g is less than at %g", sorted[i].at*100, sorted[i-1].at*100)
+		}
+	}
+
+	return sorted, nil
+}
+
+// at interpolates the latency at a quantile, anything past the last percentile is at its latency
+func (t percentileTable) at(q float64) time.Duration {
+	for i := 1; i < len(t); i++ {
+		if q <= t[i].at {
+			lower, upper := t[i-1], t[i]
+			fraction := (q - lower.at) / (upper.at - lower.at)
+
+			return lower.latency + time.Duration(fraction*float64(upper.latency-lower.latency))
+		}
+	}
+
+	return t[len(t)-1].latency
+}
+
+// failing serves the response's failure at its failure rate, and the response the rest of the time
+func (g *gnocker) failing(config *compiledConfig, options spec.Response) (func(c *fiber.Ctx), error) {
+	failure := spec.Response{StatusCode: http.StatusInternalServerError}
+	if options.Failure != nil {
+		failure = *options.Failure
+	}
+
+	fail, err := g.handler(config, failure)
+	if err != nil {
+		return nil, fmt.Errorf("failure: %w", err)
+	}
+
+	r := config.randFor("failure", options)
+	rate := options.FailureRate
+	options.FailureRate, options.Failure = 0, nil
+
+	serve, err := g.handler(config, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *fiber.Ctx) {
+		if r.float64() < rate {
+			fail(c)
+			return
+		}
+
+		serve(c)
+	}, nil
+}
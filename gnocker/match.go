@@ -0,0 +1,285 @@
+package gnocker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber"
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+type (
+	// matcher is the compiled form of a spec.Match
+	matcher struct {
+		headers []valueMatcher
+		query   []valueMatcher
+		json    []valueMatcher
+		form    []valueMatcher
+		cookies []valueMatcher
+	}
+
+	valueMatcher struct {
+		key     string
+		equals  string
+		regex   *regexp.Regexp
+		present *bool
+	}
+
+	candidate struct {
+		matcher *matcher
+		state   string
+		handler func(c *fiber.Ctx)
+	}
+)
+
+const requestJSONLocal = "gnock.requestJSON"
+
+// matchingHandler serves the first candidate matching the request and the config's state, falling back to the response
+// itself.  If the response has a match or state of its own that doesn't hold, nothing is served.
+func (g *gnocker) matchingHandler(config *compiledConfig, options spec.Response) (func(c *fiber.Ctx), error) {
+	own, err := newMatcher(options.Match)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]candidate, 0, len(options.Candidates))
+	for _, option := range options.Candidates {
+		m, err := newMatcher(option.Match)
+		if err != nil {
+			return nil, err
+		}
+
+		state := option.State
+		option.Match = nil
+		option.State = ""
+		handler, err := g.handler(config, option)
+		if err != nil {
+			return nil, err
+		}
+
+		candidates = append(candidates, candidate{matcher: m, state: state, handler: handler})
+	}
+
+	state := options.State
+	options.Match = nil
+	options.State = ""
+	options.Candidates = nil
+	fallback, err := g.handler(config, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *fiber.Ctx) {
+		if !own.matches(c) || !config.scenario.in(state) {
+			c.SendStatus(http.StatusNotFound)
+			return
+		}
+
+		for _, cand := range candidates {
+			if cand.matcher.matches(c) && config.scenario.in(cand.state) {
+				cand.handler(c)
+				return
+			}
+		}
+
+		fallback(c)
+	}, nil
+}
+
+func newMatcher(m *spec.Match) (*matcher, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	var err error
+	compiled := &matcher{}
+
+	if compiled.headers, err = newValueMatchers("header", m.Headers); err != nil {
+		return nil, err
+	}
+	if compiled.query, err = newValueMatchers("query", m.Query); err != nil {
+		return nil, err
+	}
+	if compiled.json, err = newValueMatchers("json", m.JSON); err != nil {
+		return nil, err
+	}
+	if compiled.form, err = newValueMatchers("form", m.Form); err != nil {
+		return nil, err
+	}
+	if compiled.cookies, err = newValueMatchers("cookie", m.Cookies); err != nil {
+		return nil, err
+	}
+
+	return compiled, nil
+}
+
+func newValueMatchers(kind string, values map[string]spec.ValueMatch) ([]valueMatcher, error) {
+	matchers := make([]valueMatcher, 0, len(values))
+
+	for key, value := range values {
+		vm := valueMatcher{key: key, equals: value.Equals, present: value.Present}
+
+		if value.Regex != "" {
+			re, err := regexp.Compile(value.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s regex for %s: %w", kind, key, err)
+			}
+			vm.regex = re
+		}
+
+		matchers = append(matchers, vm)
+	}
+
+	return matchers, nil
+}
+
+// matches reports whether every expectation holds for the request. A nil matcher matches everything.
+func (m *matcher) matches(c *fiber.Ctx) bool {
+	if m == nil {
+		return true
+	}
+
+	for _, vm := range m.headers {
+		value := c.Fasthttp.Request.Header.Peek(vm.key)
+		if !vm.matches(string(value), value != nil) {
+			return false
+		}
+	}
+
+	for _, vm := range m.query {
+		args := c.Fasthttp.QueryArgs()
+		if !vm.matches(string(args.Peek(vm.key)), args.Has(vm.key)) {
+			return false
+		}
+	}
+
+	for _, vm := range m.form {
+		value, found := formValue(c, vm.key)
+		if !vm.matches(value, found) {
+			return false
+		}
+	}
+
+	for _, vm := range m.cookies {
+		value := c.Fasthttp.Request.Header.Cookie(vm.key)
+		if !vm.matches(string(value), value != nil) {
+			return false
+		}
+	}
+
+	if len(m.json) > 0 {
+		body, ok := requestJSON(c)
+
+		for _, vm := range m.json {
+			var value string
+			var found bool
+			if ok {
+				value, found = jsonPath(body, vm.key)
+			}
+
+			if !vm.matches(value, found) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (vm valueMatcher) matches(value string, found bool) bool {
+	if vm.present != nil {
+		if found != *vm.present {
+			return false
+		}
+		if !found {
+			return true
+		}
+	}
+
+	if !found {
+		return false
+	}
+
+	if vm.regex != nil && !vm.regex.MatchString(value) {
+		return false
+	}
+
+	if vm.equals != "" && vm.equals != value {
+		return false
+	}
+
+	return true
+}
+
+func formValue(c *fiber.Ctx, key string) (string, bool) {
+	if args := c.Fasthttp.PostArgs(); args.Has(key) {
+		return string(args.Peek(key)), true
+	}
+
+	if form, err := c.MultipartForm(); err == nil {
+		if values := form.Value[key]; len(values) > 0 {
+			return values[0], true
+		}
+	}
+
+	return "", false
+}
+
+// requestJSON decodes the request body once per request, reporting false if it isn't JSON
+func requestJSON(c *fiber.Ctx) (interface{}, bool) {
+	if cached, ok := c.Locals(requestJSONLocal).(*interface{}); ok {
+		return *cached, *cached != nil
+	}
+
+	var body interface{}
+	decoder := json.NewDecoder(bytes.NewReader(c.Fasthttp.Request.Body()))
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		body = nil
+	}
+
+	c.Locals(requestJSONLocal, &body)
+
+	return body, body != nil
+}
+
+// jsonPath walks a decoded JSON document along a dot separated path, where numeric segments index into arrays
+func jsonPath(document interface{}, path string) (string, bool) {
+	current := document
+
+	for _, segment := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			next, ok := node[segment]
+			if !ok {
+				return "", false
+			}
+			current = next
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", false
+			}
+			current = node[i]
+		default:
+			return "", false
+		}
+	}
+
+	switch value := current.(type) {
+	case nil:
+		return "null", true
+	case string:
+		return value, true
+	case json.Number, bool:
+		return fmt.Sprint(value), true
+	default:
+		encoded, err := json.Marshal(value)
+		return string(encoded), err == nil
+	}
+}
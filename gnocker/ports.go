@@ -0,0 +1,101 @@
+package gnocker
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber"
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// portListener serves the configs that have their own address
+	portListener struct {
+		app      *fiber.App
+		listener net.Listener
+	}
+)
+
+// portIdleTimeout closes idle keep-alive connections, without it a listener's shutdown waits on them forever
+const portIdleTimeout = 30 * time.Second
+
+// configAddress is where a config with a port of its own listens
+func (g *gnocker) configAddress(host string, port int) string {
+	if port == 0 {
+		return ""
+	}
+
+	if host == "" {
+		host = g.host
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// syncPorts opens a listener for every address in the snapshot that doesn't have one and closes those no longer used.
+// If any can't be opened none are and the error is returned.  It must be called from within a registry update.
+func (g *gnocker) syncPorts(next *snapshot) error {
+	needed := map[string]bool{}
+	for _, config := range next.configs {
+		if config.address != "" {
+			needed[config.address] = true
+		}
+	}
+
+	opened := map[string]*portListener{}
+	for address := range needed {
+		if _, ok := g.ports[address]; ok {
+			continue
+		}
+
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			for _, p := range opened {
+				p.close()
+			}
+
+			return fmt.Errorf("failed to listen on %s: %w", address, err)
+		}
+
+		opened[address] = &portListener{app: newApp(portIdleTimeout), listener: listener}
+	}
+
+	for address, p := range opened {
+		g.ports[address] = p
+		g.servePort(address, p)
+	}
+
+	for address, p := range g.ports {
+		if !needed[address] {
+			g.logger.WithField("address", address).Info("closing config port")
+			p.close()
+			delete(g.ports, address)
+		}
+	}
+
+	return nil
+}
+
+// servePort starts serving the configs at an address on its listener
+func (g *gnocker) servePort(address string, p *portListener) {
+	p.app.Use(g.serve(address))
+
+	g.logger.WithFields(logrus.Fields{"address": address, "tls": g.tlsConfig != nil}).Info("config port")
+
+	go func() {
+		if err := p.app.Listener(p.listener, g.listenTLS()...); err != nil {
+			g.logger.WithError(err).WithField("address", address).Error("config port stopped")
+		}
+	}()
+}
+
+// close stops accepting connections straight away, and lets the ones open finish in the background
+func (p *portListener) close() {
+	_ = p.listener.Close()
+
+	go func() {
+		_ = p.app.Shutdown()
+	}()
+}
@@ -0,0 +1,198 @@
+package gnocker
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber"
+	"github.com/sirupsen/logrus"
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+type (
+	// recorder collects proxied responses into configurations that can be posted right back
+	recorder struct {
+		mu         sync.Mutex
+		recordings spec.Configurations
+	}
+)
+
+const (
+	// RecordedConfigName is the configuration name responses proxied by the server wide upstream are recorded under
+	RecordedConfigName = "recorded"
+)
+
+// skippedHeaders are neither forwarded nor recorded, they describe the connection rather than the resource
+var skippedHeaders = map[string]bool{
+	"Connection":          true,
+	"Content-Length":      true,
+	"Date":                true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+}
+
+func init() {
+	skippedHeaders[http.CanonicalHeaderKey(ConfigSelectHeader)] = true
+}
+
+func newRecorder() *recorder {
+	return &recorder{recordings: spec.Configurations{}}
+}
+
+func (r *recorder) record(configName, path, method string, response spec.Response) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	configuration, ok := r.recordings[configName]
+	if !ok {
+		configuration = spec.Configuration{Paths: map[string]spec.Responses{}}
+	}
+
+	if _, ok := configuration.Paths[path]; !ok {
+		configuration.Paths[path] = spec.Responses{}
+	}
+
+	configuration.Paths[path][strings.ToLower(method)] = response
+	r.recordings[configName] = configuration
+}
+
+func (r *recorder) snapshot() spec.Configurations {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recordings := spec.Configurations{}
+	for name, configuration := range r.recordings {
+		paths := map[string]spec.Responses{}
+		for path, methods := range configuration.Paths {
+			paths[path] = spec.Responses{}
+			for method, response := range methods {
+				paths[path][method] = response
+			}
+		}
+		recordings[name] = spec.Configuration{Paths: paths}
+	}
+
+	return recordings
+}
+
+func (r *recorder) clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.recordings = spec.Configurations{}
+}
+
+// WithProxy sets an upstream base URL that requests no configuration can answer are proxied to and recorded from
+func WithProxy(upstream string) Option {
+	return func(c *config) {
+		c.proxy = strings.TrimSuffix(upstream, "/")
+	}
+}
+
+// proxyFor is the upstream of the config served at the address that the request is for and the name to record under,
+// falling back to the server wide upstream
+func (g *gnocker) proxyFor(address, configName string) (upstream, recordAs string) {
+	if config := g.registry.load().proxy(address, configName); config != nil {
+		return config.proxy, config.name
+	}
+
+	return g.proxy, RecordedConfigName
+}
+
+// proxyOrNotFound forwards the request upstream, records the response and passes it back. Without an upstream it's a 404.
+func (g *gnocker) proxyOrNotFound(c *fiber.Ctx, address, configName string) {
+	upstream, recordAs := g.proxyFor(address, configName)
+	if upstream == "" {
+		c.SendStatus(http.StatusNotFound)
+		return
+	}
+
+	logger := g.logger.WithFields(logrus.Fields{
+		"config":   recordAs,
+		"upstream": upstream,
+		"path":     c.Path(),
+		"method":   c.Method(),
+	})
+	logger.Debug("proxying")
+
+	req, err := http.NewRequest(c.Method(), upstream+c.OriginalURL(), bytes.NewReader(c.Fasthttp.Request.Body()))
+	if err != nil {
+		logger.WithError(err).Error("failed to build upstream request")
+		c.SendStatus(http.StatusBadGateway)
+		return
+	}
+
+	c.Fasthttp.Request.Header.VisitAll(func(key, value []byte) {
+		if header := string(key); !skippedHeaders[header] && header != "Host" {
+			req.Header.Add(header, string(value))
+		}
+	})
+
+	res, err := g.proxyClient.Do(req)
+	if err != nil {
+		logger.WithError(err).Error("failed to reach upstream")
+		c.Send(fmt.Sprintf("Gnock gnock failed to reach %s: %s", upstream, err))
+		c.SendStatus(http.StatusBadGateway)
+		return
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		logger.WithError(err).Error("failed to read upstream response")
+		c.SendStatus(http.StatusBadGateway)
+		return
+	}
+
+	recorded := spec.Response{StatusCode: res.StatusCode, Body: string(body)}
+
+	c.Status(res.StatusCode)
+	for header, values := range res.Header {
+		if skippedHeaders[header] {
+			continue
+		}
+
+		for _, value := range values {
+			c.Fasthttp.Response.Header.Add(header, value)
+			recorded.Headers = append(recorded.Headers, map[string]string{header: value})
+		}
+	}
+	c.SendBytes(body)
+
+	g.recorder.record(recordAs, c.Path(), c.Method(), recorded)
+}
+
+func (g *gnocker) initRecordingEndpoints() {
+	recordingsPath := g.configBasePath + "/recordings"
+
+	g.logger.WithField("recordings", recordingsPath).Debug("recording endpoints")
+
+	g.admin.Get(recordingsPath, func(c *fiber.Ctx) {
+		g.sendEncoded(c, g.recorder.snapshot())
+	})
+
+	g.admin.Delete(recordingsPath, func(c *fiber.Ctx) {
+		g.recorder.clear()
+		c.SendStatus(http.StatusNoContent)
+	})
+}
+
+func newProxyClient() *http.Client {
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		// Redirects are part of what gets recorded, not something to follow
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,170 @@
+package gnocker
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+type (
+	// registry holds the configs being served.  Serving reads an immutable snapshot without locking, every change
+	// builds a new snapshot and swaps it in whole, one change at a time.
+	registry struct {
+		mu          sync.Mutex
+		current     atomic.Value
+		expirations map[string]*time.Timer
+	}
+
+	// snapshot is every config at a point in time, it must not be modified once stored
+	snapshot struct {
+		configs map[string]*compiledConfig
+		// order holds config names in the order they were added, the first with a route for a request is its default
+		order []string
+	}
+
+	// compiledConfig is a configuration with its handlers built and ready to serve
+	compiledConfig struct {
+		name   string
+		spec   spec.Configuration
+		routes []*route
+		proxy  string
+		ttl    time.Duration
+		// address is the host:port of the config's own listener, empty when it's served on the main port
+		address string
+		// seed is what the config's random sources are seeded from, 0 is random
+		seed int64
+		// funcs are the template functions of the config's handlers, which share a random source
+		funcs map[string]interface{}
+		// scenario is the config's state machine, nil if it declares no states
+		scenario *scenario
+	}
+)
+
+func newRegistry() *registry {
+	r := &registry{expirations: map[string]*time.Timer{}}
+	r.current.Store(&snapshot{configs: map[string]*compiledConfig{}})
+
+	return r
+}
+
+// load returns the current snapshot
+func (r *registry) load() *snapshot {
+	return r.current.Load().(*snapshot)
+}
+
+// update applies a change to a copy of the current snapshot and swaps it in, unless the change fails
+func (r *registry) update(change func(next *snapshot) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.load()
+	next := &snapshot{
+		configs: make(map[string]*compiledConfig, len(current.configs)),
+		order:   append([]string{}, current.order...),
+	}
+
+	for name, config := range current.configs {
+		next.configs[name] = config
+	}
+
+	if err := change(next); err != nil {
+		return err
+	}
+
+	r.current.Store(next)
+
+	return nil
+}
+
+// put adds a config to the snapshot, replacing any of the same name.  A replaced config keeps its place in the order, so
+// it stays the default for the routes it was the default for.
+func (s *snapshot) put(config *compiledConfig) {
+	if _, ok := s.configs[config.name]; !ok {
+		s.order = append(s.order, config.name)
+	}
+
+	s.configs[config.name] = config
+}
+
+// remove takes a config out of the snapshot, reporting whether it was there
+func (s *snapshot) remove(name string) bool {
+	if _, ok := s.configs[name]; !ok {
+		return false
+	}
+
+	delete(s.configs, name)
+
+	for i, configName := range s.order {
+		if configName == name {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+
+	return true
+}
+
+// lookup finds the route of the named config served at the address that matches the request.  Without a config name
+// the first config at the address, in the order they were added, that has a route for the request is used.
+func (s *snapshot) lookup(address, configName, method, path string) (*compiledConfig, *route, map[string]string) {
+	if configName != "" {
+		config, ok := s.configs[configName]
+		if !ok || config.address != address {
+			return nil, nil, nil
+		}
+
+		r, params := config.lookup(method, path)
+		return config, r, params
+	}
+
+	for _, name := range s.order {
+		config := s.configs[name]
+		if config.address != address {
+			continue
+		}
+
+		if r, params := config.lookup(method, path); r != nil {
+			return config, r, params
+		}
+	}
+
+	return nil, nil, nil
+}
+
+// proxy finds the config served at the address whose upstream a request no route answered goes to: the named one, or
+// without a name the first at the address, in the order they were added, that has an upstream
+func (s *snapshot) proxy(address, configName string) *compiledConfig {
+	if configName != "" {
+		config, ok := s.configs[configName]
+		if !ok || config.address != address || config.proxy == "" {
+			return nil
+		}
+
+		return config
+	}
+
+	for _, name := range s.order {
+		if config := s.configs[name]; config.address == address && config.proxy != "" {
+			return config
+		}
+	}
+
+	return nil
+}
+
+// lookup finds the most specific route that has a handler for the method and matches the path
+func (c *compiledConfig) lookup(method, path string) (*route, map[string]string) {
+	for _, r := range c.routes {
+		if r.handlerFor(method) == nil {
+			continue
+		}
+
+		if params, ok := r.pattern.match(path); ok {
+			return r, params
+		}
+	}
+
+	return nil, nil
+}
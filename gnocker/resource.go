@@ -0,0 +1,316 @@
+package gnocker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber"
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+type (
+	// resourceStore holds the items of a resource, in the order they were created
+	resourceStore struct {
+		mu      sync.Mutex
+		path    string
+		idField string
+		items   []map[string]interface{}
+		nextID  int
+	}
+)
+
+const (
+	defaultIDField = "id"
+	// resourceIDParam is the path param items are addressed by
+	resourceIDParam = "id"
+)
+
+// resourcePath is where a resource is served
+func resourcePath(name string, resource spec.Resource) string {
+	if resource.Path != "" {
+		return "/" + strings.Trim(resource.Path, "/")
+	}
+
+	return "/" + name
+}
+
+func newResourceStore(name string, resource spec.Resource) (*resourceStore, error) {
+	store := &resourceStore{path: resourcePath(name, resource), idField: resource.IDField, nextID: 1}
+	if store.idField == "" {
+		store.idField = defaultIDField
+	}
+
+	for i, item := range resource.Data {
+		created := copyItem(item)
+		if _, err := store.create(created); err != nil {
+			return nil, fmt.Errorf("data[%d]: %w", i, err)
+		}
+	}
+
+	return store, nil
+}
+
+// routes are the collection's list and create routes, and the item's get, replace, patch and delete routes
+func (s *resourceStore) routes() []*route {
+	itemPath := s.path + "/:" + resourceIDParam
+
+	return []*route{
+		{
+			path:    s.path,
+			pattern: newPathPattern(s.path),
+			handlers: map[string]fiber.Handler{
+				http.MethodGet:  s.serveList,
+				http.MethodPost: s.serveCreate,
+			},
+		},
+		{
+			path:    itemPath,
+			pattern: newPathPattern(itemPath),
+			handlers: map[string]fiber.Handler{
+				http.MethodGet:    s.serveGet,
+				http.MethodPut:    s.serveReplace,
+				http.MethodPatch:  s.servePatch,
+				http.MethodDelete: s.serveDelete,
+			},
+		},
+	}
+}
+
+// create adds an item, giving it the next free numeric id if it has none
+func (s *resourceStore) create(item map[string]interface{}) (map[string]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id, ok := item[s.idField]; ok {
+		if s.find(itemID(id)) >= 0 {
+			return nil, fmt.Errorf("an item with %s %v already exists", s.idField, id)
+		}
+	} else {
+		for s.find(strconv.Itoa(s.nextID)) >= 0 {
+			s.nextID++
+		}
+
+		item[s.idField] = s.nextID
+		s.nextID++
+	}
+
+	s.items = append(s.items, item)
+
+	return copyItem(item), nil
+}
+
+// find is the index of the item with the id, -1 if there isn't one.  The store must be locked.
+func (s *resourceStore) find(id string) int {
+	for i, item := range s.items {
+		if itemID(item[s.idField]) == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// update replaces the item with the id with what change makes of a copy of it, reporting whether there was one
+func (s *resourceStore) update(id string, change func(item map[string]interface{}) map[string]interface{}) (map[string]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.find(id)
+	if i < 0 {
+		return nil, false
+	}
+
+	updated := change(copyItem(s.items[i]))
+	// Items keep their id whatever the body says
+	updated[s.idField] = s.items[i][s.idField]
+	s.items[i] = updated
+
+	return copyItem(updated), true
+}
+
+func (s *resourceStore) serveList(c *fiber.Ctx) {
+	offset, limit := 0, -1
+	filters := map[string]string{}
+	var badQuery error
+
+	c.Fasthttp.QueryArgs().VisitAll(func(key, value []byte) {
+		var n *int
+		switch string(key) {
+		case "offset":
+			n = &offset
+		case "limit":
+			n = &limit
+		default:
+			filters[string(key)] = string(value)
+			return
+		}
+
+		parsed, err := strconv.Atoi(string(value))
+		if (err != nil || parsed < 0) && badQuery == nil {
+			badQuery = fmt.Errorf("%s must be zero or a positive number", key)
+		}
+		*n = parsed
+	})
+
+	if badQuery != nil {
+		c.Status(http.StatusBadRequest).Send(badQuery.Error())
+		return
+	}
+
+	s.mu.Lock()
+	matching := []map[string]interface{}{}
+	for _, item := range s.items {
+		if itemMatches(item, filters) {
+			matching = append(matching, copyItem(item))
+		}
+	}
+	s.mu.Unlock()
+
+	c.Set("X-Total-Count", strconv.Itoa(len(matching)))
+
+	matching = matching[min(offset, len(matching)):]
+	if limit >= 0 {
+		matching = matching[:min(limit, len(matching))]
+	}
+
+	sendJSON(c, http.StatusOK, matching)
+}
+
+func (s *resourceStore) serveGet(c *fiber.Ctx) {
+	s.mu.Lock()
+	var item map[string]interface{}
+	if i := s.find(routeParams(c)[resourceIDParam]); i >= 0 {
+		item = copyItem(s.items[i])
+	}
+	s.mu.Unlock()
+
+	if item == nil {
+		c.SendStatus(http.StatusNotFound)
+		return
+	}
+
+	sendJSON(c, http.StatusOK, item)
+}
+
+func (s *resourceStore) serveCreate(c *fiber.Ctx) {
+	body, ok := requestItem(c)
+	if !ok {
+		return
+	}
+
+	item, err := s.create(body)
+	if err != nil {
+		c.Status(http.StatusConflict).Send(err.Error())
+		return
+	}
+
+	c.Set(fiber.HeaderLocation, s.path+"/"+itemID(item[s.idField]))
+	sendJSON(c, http.StatusCreated, item)
+}
+
+func (s *resourceStore) serveReplace(c *fiber.Ctx) {
+	body, ok := requestItem(c)
+	if !ok {
+		return
+	}
+
+	s.sendUpdated(c, func(map[string]interface{}) map[string]interface{} {
+		return body
+	})
+}
+
+func (s *resourceStore) servePatch(c *fiber.Ctx) {
+	body, ok := requestItem(c)
+	if !ok {
+		return
+	}
+
+	s.sendUpdated(c, func(item map[string]interface{}) map[string]interface{} {
+		for key, value := range body {
+			item[key] = value
+		}
+
+		return item
+	})
+}
+
+func (s *resourceStore) sendUpdated(c *fiber.Ctx, change func(item map[string]interface{}) map[string]interface{}) {
+	item, ok := s.update(routeParams(c)[resourceIDParam], change)
+	if !ok {
+		c.SendStatus(http.StatusNotFound)
+		return
+	}
+
+	sendJSON(c, http.StatusOK, item)
+}
+
+func (s *resourceStore) serveDelete(c *fiber.Ctx) {
+	s.mu.Lock()
+	i := s.find(routeParams(c)[resourceIDParam])
+	if i >= 0 {
+		s.items = append(s.items[:i], s.items[i+1:]...)
+	}
+	s.mu.Unlock()
+
+	if i < 0 {
+		c.SendStatus(http.StatusNotFound)
+		return
+	}
+
+	c.SendStatus(http.StatusNoContent)
+}
+
+// requestItem decodes the request body as an item, responding with a bad request if it isn't a JSON object
+func requestItem(c *fiber.Ctx) (map[string]interface{}, bool) {
+	item := map[string]interface{}{}
+
+	decoder := json.NewDecoder(bytes.NewReader(c.Fasthttp.Request.Body()))
+	decoder.UseNumber()
+	if err := decoder.Decode(&item); err != nil {
+		c.Status(http.StatusBadRequest).Send("the body must be a JSON object: " + err.Error())
+		return nil, false
+	}
+
+	return item, true
+}
+
+func sendJSON(c *fiber.Ctx, status int, v interface{}) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		c.Status(http.StatusInternalServerError).Send(err.Error())
+		return
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	c.Status(status).SendBytes(encoded)
+}
+
+// itemMatches reports whether each filtered field of the item has the value given
+func itemMatches(item map[string]interface{}, filters map[string]string) bool {
+	for field, value := range filters {
+		if fieldValue, ok := item[field]; !ok || itemID(fieldValue) != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// itemID is an id, or any other field value, as it appears in a path or query
+func itemID(id interface{}) string {
+	return fmt.Sprint(id)
+}
+
+func copyItem(item map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(item))
+	for key, value := range item {
+		copied[key] = value
+	}
+
+	return copied
+}
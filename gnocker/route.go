@@ -0,0 +1,263 @@
+package gnocker
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber"
+)
+
+type (
+	// route is a configured path with its handlers by method
+	route struct {
+		path     string
+		pattern  pathPattern
+		handlers map[string]fiber.Handler
+	}
+
+	// pathPattern matches request paths the way fiber does: case insensitive, ignoring trailing slashes, with :params and
+	// optional :params? delimited by /, - or ., and a * wildcard that takes what the segments after it leave over
+	pathPattern []pathSegment
+
+	// pathSegment is either a literal, which can span delimiters, or a param, ending at the delimiter that follows it
+	pathSegment struct {
+		literal  string
+		param    string
+		optional bool
+		wildcard bool
+		last     bool
+		end      byte
+	}
+)
+
+const (
+	routeParamsLocal = "gnock.routeParams"
+	// routeDelimiters end params, a / is the only one that can't be part of a param's value
+	routeDelimiters = "/-."
+)
+
+func newPathPattern(path string) pathPattern {
+	var pattern pathPattern
+
+	path = trimPath(path)
+	for len(path) > 0 {
+		end := strings.IndexAny(path, routeDelimiters)
+		part := path
+		if end != -1 {
+			part = path[:end]
+		}
+
+		if part == "" {
+			path = path[1:]
+			continue
+		}
+
+		switch {
+		case part[0] == '*':
+			pattern = append(pattern, pathSegment{param: part, wildcard: true, optional: part == "*"})
+		case part[0] == ':':
+			name := part[1:]
+			optional := strings.HasSuffix(name, "?")
+			pattern = append(pattern, pathSegment{param: strings.TrimSuffix(name, "?"), optional: optional})
+		case len(pattern) > 0 && pattern[len(pattern)-1].param == "":
+			// Literals run on until the next param
+			previous := &pattern[len(pattern)-1]
+			previous.literal += string(previous.end) + lowerASCII(part)
+		default:
+			pattern = append(pattern, pathSegment{literal: lowerASCII(part)})
+		}
+
+		segment := &pattern[len(pattern)-1]
+		if end == -1 {
+			segment.end = '/'
+			break
+		}
+
+		segment.end = path[end]
+		path = path[end+1:]
+	}
+
+	if len(pattern) > 0 {
+		pattern[len(pattern)-1].last = true
+	}
+
+	return pattern
+}
+
+// match reports whether the request path fits the pattern and the values of any params in it
+func (p pathPattern) match(path string) (map[string]string, bool) {
+	original := strings.TrimPrefix(trimPath(path), "/")
+	rest := lowerASCII(original)
+	params := map[string]string{}
+
+	var start int
+	for index, segment := range p {
+		remaining := len(rest)
+
+		var length int
+		if segment.param != "" {
+			switch {
+			case segment.wildcard && segment.last:
+				length = remaining
+			case segment.wildcard:
+				length = wildcardLength(rest, p, index)
+			default:
+				length = strings.IndexByte(rest, segment.end)
+			}
+			if length == -1 {
+				length = remaining
+			}
+
+			if length == 0 && !segment.optional {
+				return nil, false
+			}
+
+			// A param ending at a - or . can't swallow the / before it
+			if length > 0 && segment.end != '/' && rest[length-1] == '/' {
+				return nil, false
+			}
+
+			params[segment.param] = original[start : start+length]
+		} else {
+			length = len(segment.literal)
+			if remaining < length ||
+				(length == 0 && remaining > 0) ||
+				rest[:length] != segment.literal ||
+				(remaining > length && rest[length] != segment.end) {
+				return nil, false
+			}
+		}
+
+		// Step over the segment and its delimiter
+		if remaining > 0 {
+			step := length + 1
+			if segment.last || remaining < step {
+				step = length
+			}
+
+			start += step
+			rest = rest[step:]
+		}
+	}
+
+	if rest != "" {
+		return nil, false
+	}
+
+	return params, true
+}
+
+// wildcardLength is how much of the path a wildcard before other segments takes, matching right to left so the
+// segments after it get what they need: /api/*/:id takes joker/batman for /api/joker/batman/1
+func wildcardLength(path string, pattern pathPattern, index int) int {
+	end := pattern[index].end
+
+	needed := 0
+	for _, segment := range pattern[index+1:] {
+		if segment.end == end {
+			needed++
+		}
+	}
+
+	for {
+		i := strings.LastIndexByte(path, end)
+		if i != -1 {
+			path = path[:i]
+		}
+
+		needed--
+		if needed <= 0 || i == -1 {
+			break
+		}
+	}
+
+	return len(path)
+}
+
+// specificity ranks patterns so literal parts win over params, and params over wildcards
+func (p pathPattern) specificity() (literals, params int, wildcard bool) {
+	for _, segment := range p {
+		switch {
+		case segment.wildcard:
+			wildcard = true
+		case segment.param != "":
+			params++
+		default:
+			literals += 1 + strings.Count(segment.literal, "/") +
+				strings.Count(segment.literal, "-") + strings.Count(segment.literal, ".")
+		}
+	}
+
+	return literals, params, wildcard
+}
+
+// trimPath makes the path start with a / and drops any trailing ones
+func trimPath(path string) string {
+	path = "/" + strings.TrimLeft(path, "/")
+	if len(path) > 1 {
+		path = strings.TrimRight(path, "/")
+	}
+
+	return path
+}
+
+// lowerASCII lowercases only ASCII letters, like fiber, so the matched path and the original line up byte for byte
+func lowerASCII(s string) string {
+	lower := []byte(s)
+	for i, b := range lower {
+		if 'A' <= b && b <= 'Z' {
+			lower[i] = b + 'a' - 'A'
+		}
+	}
+
+	return string(lower)
+}
+
+// sortRoutes orders routes most specific first, so the one matching a request is the best fit for it
+func sortRoutes(routes []*route) {
+	sort.SliceStable(routes, func(i, j int) bool {
+		li, pi, wi := routes[i].pattern.specificity()
+		lj, pj, wj := routes[j].pattern.specificity()
+
+		switch {
+		case wi != wj:
+			return wj
+		case li != lj:
+			return li > lj
+		case pi != pj:
+			return pi > pj
+		default:
+			return routes[i].path < routes[j].path
+		}
+	})
+}
+
+// handlerFor is the handler for the method, GET handlers answer HEAD requests when there is no HEAD handler
+func (r *route) handlerFor(method string) fiber.Handler {
+	if handler, ok := r.handlers[method]; ok {
+		return handler
+	}
+
+	if method == fiber.MethodHead {
+		return r.handlers[fiber.MethodGet]
+	}
+
+	return nil
+}
+
+// routeParams are the path params matched for the request, empty if it didn't match a route
+func routeParams(c *fiber.Ctx) map[string]string {
+	if params, ok := c.Locals(routeParamsLocal).(map[string]string); ok {
+		return params
+	}
+
+	return map[string]string{}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,164 @@
+package gnocker
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber"
+	"github.com/zerbitx/gnockgnock/encode"
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+type (
+	// ScenarioState is the state a config's scenario is in
+	ScenarioState struct {
+		State string `json:"state" yaml:"state"`
+	}
+
+	// scenario is the state machine of a config, moved along by the responses it serves
+	scenario struct {
+		mu      sync.Mutex
+		states  map[string]bool
+		initial string
+		current string
+	}
+)
+
+// ErrUnknownConfig is returned for a config that hasn't been added
+var ErrUnknownConfig = errors.New("unknown config")
+
+// newScenario starts in the first of the states, it's nil when there are none
+func newScenario(states []string) *scenario {
+	if len(states) == 0 {
+		return nil
+	}
+
+	s := &scenario{states: map[string]bool{}, initial: states[0], current: states[0]}
+	for _, state := range states {
+		s.states[state] = true
+	}
+
+	return s
+}
+
+// in reports whether the scenario is in the state, every state is fine by a response without one
+func (s *scenario) in(state string) bool {
+	if state == "" {
+		return true
+	}
+
+	if s == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.current == state
+}
+
+func (s *scenario) state() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.current
+}
+
+// moveTo changes the state, the empty state being the initial one
+func (s *scenario) moveTo(state string) error {
+	if state == "" {
+		state = s.initial
+	}
+
+	if !s.states[state] {
+		return fmt.Errorf("unknown state %s", state)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.current = state
+
+	return nil
+}
+
+// State returns the state the named config's scenario is in, false if it has no scenario
+func (g *gnocker) State(configName string) (string, bool) {
+	config, ok := g.registry.load().configs[configName]
+	if !ok || config.scenario == nil {
+		return "", false
+	}
+
+	return config.scenario.state(), true
+}
+
+// SetState moves the named config's scenario to a state, or back to where it started when the state is empty
+func (g *gnocker) SetState(configName, state string) error {
+	config, ok := g.registry.load().configs[configName]
+	if !ok {
+		return ErrUnknownConfig
+	}
+
+	if config.scenario == nil {
+		return fmt.Errorf("%s has no states", configName)
+	}
+
+	return config.scenario.moveTo(state)
+}
+
+func (g *gnocker) initScenarioEndpoints() {
+	statePath := g.configBasePath + "/:name/state"
+
+	g.logger.WithField("state", statePath).Debug("scenario endpoints")
+
+	g.admin.Get(statePath, func(c *fiber.Ctx) {
+		g.sendState(c)
+	})
+
+	g.admin.Put(statePath, func(c *fiber.Ctx) {
+		state := ScenarioState{}
+		if err := spec.Decode(strings.NewReader(c.Body()), &state, g.strictFor(c)); err != nil {
+			g.logger.WithError(err).Error("failed to decode state")
+			c.Send(err.Error())
+			c.SendStatus(http.StatusBadRequest)
+			return
+		}
+
+		g.setState(c, state.State)
+	})
+
+	g.admin.Delete(statePath, func(c *fiber.Ctx) {
+		g.setState(c, "")
+	})
+}
+
+// setState moves the scenario of the config in the path, responding with the state it's now in
+func (g *gnocker) setState(c *fiber.Ctx, state string) {
+	err := g.SetState(c.Params("name"), state)
+
+	switch {
+	case errors.Is(err, ErrUnknownConfig):
+		c.SendStatus(http.StatusNotFound)
+	case err != nil:
+		c.Send(err.Error())
+		c.SendStatus(http.StatusBadRequest)
+	default:
+		g.sendState(c)
+	}
+}
+
+func (g *gnocker) sendState(c *fiber.Ctx) {
+	state, ok := g.State(c.Params("name"))
+	if !ok {
+		c.SendStatus(http.StatusNotFound)
+		return
+	}
+
+	if err := encode.JSONIndented(ScenarioState{State: state}, c.Fasthttp.Response.BodyWriter()); err != nil {
+		g.logger.WithError(err).Error("Failed to encode response")
+		c.SendStatus(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,65 @@
+package gnocker
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gofiber/fiber"
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+// sequenceHandler walks through the responses of a sequence, one per call, and decides what to do once they run out
+// according to the sequence mode.
+func (g *gnocker) sequenceHandler(config *compiledConfig, options spec.Response) (func(c *fiber.Ctx), error) {
+	mode, err := sequenceMode(options.SequenceMode)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := make([]func(c *fiber.Ctx), 0, len(options.Sequence))
+	for _, step := range options.Sequence {
+		handler, err := g.handler(config, step)
+		if err != nil {
+			return nil, err
+		}
+
+		steps = append(steps, handler)
+	}
+
+	var mu sync.Mutex
+	next := 0
+
+	return func(c *fiber.Ctx) {
+		mu.Lock()
+		step := next
+		switch {
+		case next < len(steps)-1:
+			next++
+		case mode == spec.SequenceModeLoop:
+			next = 0
+		case mode == spec.SequenceModeNotFound:
+			next = len(steps)
+		}
+		mu.Unlock()
+
+		if step >= len(steps) {
+			c.SendStatus(http.StatusNotFound)
+			return
+		}
+
+		steps[step](c)
+	}, nil
+}
+
+// sequenceMode validates a sequence mode, defaulting to last
+func sequenceMode(mode string) (string, error) {
+	switch mode {
+	case "":
+		return spec.SequenceModeLast, nil
+	case spec.SequenceModeLast, spec.SequenceModeLoop, spec.SequenceModeNotFound:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown sequence mode %s", mode)
+	}
+}
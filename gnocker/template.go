@@ -0,0 +1,163 @@
+package gnocker
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/gofiber/fiber"
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+type (
+	// responseTemplate is a parsed template of either engine
+	responseTemplate interface {
+		Execute(w io.Writer, data interface{}) error
+	}
+
+	// responseValue is a header or event field to respond with, its value templated if tpl is set
+	responseValue struct {
+		name  string
+		value string
+		tpl   responseTemplate
+	}
+)
+
+// templateEngine is the engine a response's templates are executed with.  Unless the response picks one html/template
+// is used for HTML and text/template for everything else, so JSON and the like aren't HTML escaped.
+func templateEngine(options spec.Response) (string, error) {
+	switch options.TemplateEngine {
+	case spec.TemplateEngineText, spec.TemplateEngineHTML:
+		return options.TemplateEngine, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unknown template engine %s, expected text or html", options.TemplateEngine)
+	}
+
+	for _, headers := range options.Headers {
+		for header, value := range headers {
+			if strings.EqualFold(header, fiber.HeaderContentType) && strings.Contains(strings.ToLower(value), "html") {
+				return spec.TemplateEngineHTML, nil
+			}
+		}
+	}
+
+	return spec.TemplateEngineText, nil
+}
+
+// parseTemplate parses a template of a response with its engine and the config's functions
+func parseTemplate(configName, engine, text string, funcs map[string]interface{}) (responseTemplate, error) {
+	if engine == spec.TemplateEngineHTML {
+		return htmltemplate.New(configName).Funcs(funcs).Parse(text)
+	}
+
+	return texttemplate.New(configName).Funcs(funcs).Parse(text)
+}
+
+// templateData is what a template is executed with: the request as .Params, .Query, .Headers, .Cookies, .Body, .JSON,
+// .Method, .Path and .Config, and each path param by name at the top level, where it wins over any of those
+func templateData(c *fiber.Ctx, configName string) map[string]interface{} {
+	params := routeParams(c)
+
+	query := map[string]string{}
+	c.Fasthttp.QueryArgs().VisitAll(func(key, value []byte) {
+		if _, ok := query[string(key)]; !ok {
+			query[string(key)] = string(value)
+		}
+	})
+
+	headers := map[string]string{}
+	c.Fasthttp.Request.Header.VisitAll(func(key, value []byte) {
+		if _, ok := headers[string(key)]; !ok {
+			headers[string(key)] = string(value)
+		}
+	})
+
+	body, _ := requestJSON(c)
+
+	data := map[string]interface{}{
+		"Params":  params,
+		"Query":   query,
+		"Headers": headers,
+		"Cookies": requestCookies(c),
+		"Body":    string(c.Fasthttp.Request.Body()),
+		"JSON":    body,
+		"Method":  c.Method(),
+		"Path":    c.Path(),
+		"Config":  configName,
+	}
+
+	for name, value := range params {
+		data[name] = value
+	}
+
+	return data
+}
+
+// newResponseHeaders parses the values of headers that are templates, which headers are when they contain {{
+func newResponseHeaders(configName string, headers []map[string]string, funcs map[string]interface{}) ([]responseValue, error) {
+	var compiled []responseValue
+
+	for _, hs := range headers {
+		for name, value := range hs {
+			header, err := newResponseValue(configName, name, value, funcs)
+			if err != nil {
+				return nil, fmt.Errorf("header %s: %w", name, err)
+			}
+
+			compiled = append(compiled, header)
+		}
+	}
+
+	return compiled, nil
+}
+
+// newResponseValue parses the value when it is a template, which it is when it contains {{
+func newResponseValue(configName, name, value string, funcs map[string]interface{}) (responseValue, error) {
+	v := responseValue{name: name, value: value}
+
+	if strings.Contains(value, "{{") {
+		tpl, err := parseTemplate(configName, spec.TemplateEngineText, value, funcs)
+		if err != nil {
+			return responseValue{}, err
+		}
+
+		v.tpl = tpl
+	}
+
+	return v, nil
+}
+
+// render is the value for a request
+func (v responseValue) render(data interface{}) (string, error) {
+	if v.tpl == nil {
+		return v.value, nil
+	}
+
+	return renderTemplate(v.tpl, data)
+}
+
+// renderStatusCode renders a status code template, which has to give a valid status code
+func renderStatusCode(tpl responseTemplate, data interface{}) (int, error) {
+	rendered, err := renderTemplate(tpl, data)
+	if err != nil {
+		return 0, err
+	}
+
+	status, err := strconv.Atoi(strings.TrimSpace(rendered))
+	if err != nil || status < 100 || status > 599 {
+		return 0, fmt.Errorf("status code template gave %q, not a status code", rendered)
+	}
+
+	return status, nil
+}
+
+func renderTemplate(tpl responseTemplate, data interface{}) (string, error) {
+	var rendered strings.Builder
+	err := tpl.Execute(&rendered, data)
+
+	return rendered.String(), err
+}
@@ -0,0 +1,95 @@
+package gnocker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber"
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+type (
+	// throttle drips a body out a chunk at a time
+	throttle struct {
+		chunkSize int
+		delay     time.Duration
+	}
+)
+
+const defaultChunkSize = 1024
+
+func newThrottle(options *spec.Throttle) (*throttle, error) {
+	if options == nil {
+		return nil, nil
+	}
+
+	if options.ChunkSize < 0 {
+		return nil, errors.New("chunkSize can't be negative")
+	}
+
+	t := &throttle{chunkSize: options.ChunkSize}
+
+	switch {
+	case options.BytesPerSecond != 0 && options.ChunkDelay != "":
+		return nil, errors.New("a throttle has either bytesPerSecond or a chunkDelay")
+	case options.BytesPerSecond < 0:
+		return nil, errors.New("bytesPerSecond can't be negative")
+	case options.BytesPerSecond > 0:
+		if t.chunkSize == 0 {
+			// Ten chunks a second, but at least a byte at a time
+			t.chunkSize = options.BytesPerSecond / 10
+			if t.chunkSize == 0 {
+				t.chunkSize = 1
+			}
+		}
+
+		t.delay = time.Duration(t.chunkSize) * time.Second / time.Duration(options.BytesPerSecond)
+	case options.ChunkDelay != "":
+		delay, err := time.ParseDuration(options.ChunkDelay)
+		if err != nil {
+			return nil, fmt.Errorf("chunkDelay: %w", err)
+		}
+
+		t.delay = delay
+	default:
+		return nil, errors.New("a throttle needs bytesPerSecond or a chunkDelay")
+	}
+
+	if t.chunkSize == 0 {
+		t.chunkSize = defaultChunkSize
+	}
+
+	return t, nil
+}
+
+// apply streams the body the handler has written, chunked and with its headers sent right away so clients see it
+// trickle in
+func (t *throttle) apply(c *fiber.Ctx) {
+	body := append([]byte{}, c.Fasthttp.Response.Body()...)
+	if len(body) == 0 {
+		return
+	}
+
+	c.Fasthttp.Response.ImmediateHeaderFlush = true
+	c.Fasthttp.SetBodyStreamWriter(func(w *bufio.Writer) {
+		for len(body) > 0 {
+			chunk := body[:min(t.chunkSize, len(body))]
+			body = body[len(chunk):]
+
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+
+			// Flushing hands the chunk to the connection, a failure means the client is gone
+			if err := w.Flush(); err != nil {
+				return
+			}
+
+			if len(body) > 0 {
+				time.Sleep(t.delay)
+			}
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package gnocker
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"github.com/gofiber/fiber"
+	"github.com/zerbitx/gnockgnock/certs"
+	"github.com/zerbitx/gnockgnock/encode"
+)
+
+type (
+	// ClientCertificate is a client certificate and its key, PEM encoded
+	ClientCertificate struct {
+		Cert string `json:"cert"`
+		Key  string `json:"key"`
+	}
+)
+
+// WithTLS serves the mocks over TLS
+func WithTLS(tlsConfig *tls.Config) Option {
+	return func(c *config) {
+		c.tlsConfig = tlsConfig
+	}
+}
+
+// WithAuthority exposes the certificate authority behind a generated TLS certificate on the config endpoints, so tests
+// can trust it and get client certificates from it
+func WithAuthority(authority *certs.Authority) Option {
+	return func(c *config) {
+		c.authority = authority
+	}
+}
+
+// listenTLS is the optional TLS config argument for fiber's Listen
+func (g *gnocker) listenTLS() []*tls.Config {
+	if g.tlsConfig == nil {
+		return nil
+	}
+
+	return []*tls.Config{g.tlsConfig}
+}
+
+func (g *gnocker) initTLSEndpoints() {
+	caPath := g.configBasePath + "/tls/ca.pem"
+	clientCertPath := g.configBasePath + "/tls/client-cert"
+
+	g.logger.WithField("ca", caPath).WithField("clientCert", clientCertPath).Debug("tls endpoints")
+
+	g.admin.Get(caPath, func(c *fiber.Ctx) {
+		if g.authority == nil {
+			c.SendStatus(http.StatusNotFound)
+			return
+		}
+
+		c.Set(fiber.HeaderContentType, "application/x-pem-file")
+		c.SendBytes(g.authority.PEM())
+	})
+
+	g.admin.Post(clientCertPath, func(c *fiber.Ctx) {
+		if g.authority == nil {
+			c.SendStatus(http.StatusNotFound)
+			return
+		}
+
+		certPEM, keyPEM, err := g.authority.IssueClient(c.Query("name", "gnockgnock-client"))
+		if err != nil {
+			g.logger.WithError(err).Error("failed to issue client certificate")
+			c.SendStatus(http.StatusInternalServerError)
+			return
+		}
+
+		c.Status(http.StatusCreated)
+
+		err = encode.JSONIndented(ClientCertificate{Cert: string(certPEM), Key: string(keyPEM)}, c.Fasthttp.Response.BodyWriter())
+		if err != nil {
+			g.logger.WithError(err).Error("Failed to encode response")
+			c.SendStatus(http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,346 @@
+package gnocker
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+type (
+	// ValidationError is a single problem with a configuration, located by config name, path, method and field
+	ValidationError struct {
+		Config  string `json:"config"`
+		Path    string `json:"path,omitempty"`
+		Method  string `json:"method,omitempty"`
+		Field   string `json:"field,omitempty"`
+		Message string `json:"message"`
+	}
+
+	// ValidationErrors is every problem found in a set of configurations
+	ValidationErrors []ValidationError
+
+	// validation collects the problems of a single config
+	validation struct {
+		config   string
+		path     string
+		method   string
+		problems ValidationErrors
+		// reserved are the ports the server itself listens on, by what they are used for
+		reserved map[int]string
+		// states are the states the config declares for its scenario
+		states map[string]bool
+	}
+)
+
+// reservedConfigNames are the admin endpoints on the config base path, where they would shadow configs of the same name
+var reservedConfigNames = map[string]bool{"journal": true, "verify": true, "recordings": true, "tls": true}
+
+func (e ValidationError) Error() string {
+	location := []string{e.Config}
+	for _, part := range []string{e.Path, e.Method, e.Field} {
+		if part != "" {
+			location = append(location, part)
+		}
+	}
+
+	return fmt.Sprintf("%s: %s", strings.Join(location, " "), e.Message)
+}
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, problem := range e {
+		messages = append(messages, problem.Error())
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Validate checks every configuration for problems that would keep it from being served, without adding any of them
+func (g *gnocker) Validate(operations spec.Configurations) ValidationErrors {
+	var problems ValidationErrors
+
+	for configName, operation := range operations {
+		v := &validation{config: configName, reserved: g.reservedPorts()}
+		v.configuration(operation)
+		problems = append(problems, v.problems...)
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i], problems[j]
+		if a.Config != b.Config {
+			return a.Config < b.Config
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return a.Field < b.Field
+	})
+
+	return problems
+}
+
+func (v *validation) add(field, format string, args ...interface{}) {
+	v.problems = append(v.problems, ValidationError{
+		Config:  v.config,
+		Path:    v.path,
+		Method:  v.method,
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validation) configuration(operation spec.Configuration) {
+	if reservedConfigNames[v.config] {
+		v.add("", "%s is the name of an admin endpoint, a config can't have it", v.config)
+	}
+
+	v.duration("ttl", operation.TTL)
+
+	if operation.Proxy != "" {
+		if u, err := url.Parse(operation.Proxy); err != nil || u.Scheme == "" || u.Host == "" {
+			v.add("proxy", "%q is not an absolute URL", operation.Proxy)
+		}
+	}
+
+	switch {
+	case operation.Port < 0 || operation.Port > 65535:
+		v.add("port", "%d is not a valid port", operation.Port)
+	case v.reserved[operation.Port] != "":
+		v.add("port", "%d is the %s port", operation.Port, v.reserved[operation.Port])
+	case operation.Host != "" && operation.Port == 0:
+		v.add("host", "a host needs a port to listen on")
+	}
+
+	v.states = map[string]bool{}
+	for i, state := range operation.States {
+		switch {
+		case state == "":
+			v.add(fmt.Sprintf("states[%d]", i), "a state needs a name")
+		case v.states[state]:
+			v.add(fmt.Sprintf("states[%d]", i), "%s is declared more than once", state)
+		}
+
+		v.states[state] = true
+	}
+
+	paths := map[string]string{}
+	for name, resource := range operation.Resources {
+		field := join("resources", name)
+		path := resourcePath(name, resource)
+
+		if strings.ContainsAny(path, ":*") {
+			v.add(join(field, "path"), "%s can't have params or wildcards", path)
+		}
+
+		if other, ok := paths[path]; ok {
+			v.add(join(field, "path"), "%s is also the path of %s", path, other)
+		}
+		paths[path] = name
+
+		if _, err := newResourceStore(name, resource); err != nil {
+			v.add(field, "%s", err)
+		}
+	}
+
+	for path, responses := range operation.Paths {
+		v.path = path
+
+		// Methods are case insensitive, a path can't give one twice
+		given := map[string][]string{}
+		for m := range responses {
+			given[strings.ToUpper(m)] = append(given[strings.ToUpper(m)], m)
+		}
+
+		for method, keys := range given {
+			if len(keys) > 1 {
+				sort.Strings(keys)
+				v.method = method
+				v.add("", "given more than once, as %s", strings.Join(keys, " and "))
+			}
+		}
+
+		for m, response := range responses {
+			v.method = strings.ToUpper(m)
+
+			if !methods[v.method] {
+				v.add("", "unknown method %s", m)
+				continue
+			}
+
+			v.response("", response)
+		}
+
+		v.method = ""
+	}
+
+	v.path = ""
+}
+
+// response checks a response and everything nested in it, field is where it sits within the method's response
+func (v *validation) response(field string, response spec.Response) {
+	v.duration(join(field, "delay"), response.Delay)
+
+	if response.Latency != nil {
+		if _, err := newLatency(response, newSeededRand(0)); err != nil {
+			v.add(join(field, "latency"), "%s", err)
+		}
+	}
+
+	if response.FailureRate < 0 || response.FailureRate > 1 {
+		v.add(join(field, "failureRate"), "%g is not a rate between 0 and 1", response.FailureRate)
+	}
+
+	if response.Failure != nil {
+		v.response(join(field, "failure"), *response.Failure)
+	}
+
+	if response.StatusCode != 0 && (response.StatusCode < 100 || response.StatusCode > 599) {
+		v.add(join(field, "statusCode"), "%d is not a valid status code", response.StatusCode)
+	}
+
+	for i, cookie := range response.Cookies {
+		if _, err := newResponseCookie(cookie); err != nil {
+			v.add(join(field, fmt.Sprintf("cookies[%d]", i)), "%s", err)
+		}
+	}
+
+	if response.LastModified != "" {
+		if _, err := parseHTTPTime(response.LastModified); err != nil {
+			v.add(join(field, "lastModified"), "%s", err)
+		}
+	}
+
+	funcs := templateFuncs(newSeededRand(0))
+
+	engine, err := templateEngine(response)
+	if err != nil {
+		v.add(join(field, "templateEngine"), "%s", err)
+	} else if response.BodyTemplate != "" {
+		if _, err := parseTemplate(v.config, engine, response.BodyTemplate, funcs); err != nil {
+			v.add(join(field, "bodyTemplate"), "%s", err)
+		}
+	}
+
+	if response.StatusCodeTemplate != "" {
+		if _, err := parseTemplate(v.config, spec.TemplateEngineText, response.StatusCodeTemplate, funcs); err != nil {
+			v.add(join(field, "statusCodeTemplate"), "%s", err)
+		}
+	}
+
+	if _, err := newResponseHeaders(v.config, response.Headers, funcs); err != nil {
+		v.add(join(field, "responseHeaders"), "%s", err)
+	}
+
+	if _, err := newFault(response.Fault); err != nil {
+		v.add(join(field, "fault"), "%s", err)
+	}
+
+	if _, err := newEventStream(v.config, response, funcs); err != nil {
+		v.add(join(field, "events"), "%s", err)
+	} else if len(response.Events) > 0 {
+		v.exclusive(field, "events", map[string]bool{
+			"body":         response.Body != "",
+			"bodyTemplate": response.BodyTemplate != "",
+			"throttle":     response.Throttle != nil,
+			"fault":        response.Fault != "",
+			// A stream has no body to tag, or to leave out on a 304
+			"etag":         response.ETag != "",
+			"lastModified": response.LastModified != "",
+		})
+	}
+
+	if response.WebSocket != nil {
+		if _, err := newWebSocketScript(v.config, response.WebSocket, funcs); err != nil {
+			v.add(join(field, "webSocket"), "%s", err)
+		}
+
+		v.exclusive(field, "webSocket", map[string]bool{
+			"body":         response.Body != "",
+			"bodyTemplate": response.BodyTemplate != "",
+			"events":       len(response.Events) > 0,
+			"throttle":     response.Throttle != nil,
+			"fault":        response.Fault != "",
+			"sequence":     len(response.Sequence) > 0,
+		})
+	}
+
+	if _, err := newThrottle(response.Throttle); err != nil {
+		v.add(join(field, "throttle"), "%s", err)
+	} else if response.Throttle != nil && response.Fault != "" {
+		v.add(join(field, "throttle"), "a faulty response is never sent, it can't be throttled")
+	}
+
+	v.state(join(field, "state"), response.State)
+	v.state(join(field, "nextState"), response.NextState)
+
+	if response.NextState != "" && len(response.Sequence) > 0 {
+		v.add(join(field, "nextState"), "a sequence moves the scenario with the nextState of its steps")
+	}
+
+	if _, err := sequenceMode(response.SequenceMode); err != nil {
+		v.add(join(field, "sequenceMode"), "%s", err)
+	}
+
+	if _, err := newMatcher(response.Match); err != nil {
+		v.add(join(field, "match"), "%s", err)
+	}
+
+	for i, step := range response.Sequence {
+		v.response(join(field, fmt.Sprintf("sequence[%d]", i)), step)
+	}
+
+	for i, candidate := range response.Candidates {
+		v.response(join(field, fmt.Sprintf("candidates[%d]", i)), candidate)
+	}
+}
+
+// exclusive reports each of the fields that is set alongside one that replaces the whole response
+func (v *validation) exclusive(field, by string, set map[string]bool) {
+	for name, isSet := range set {
+		if isSet {
+			v.add(join(field, name), "can't be set along with %s", by)
+		}
+	}
+}
+
+func (v *validation) state(field, state string) {
+	if state != "" && !v.states[state] {
+		v.add(field, "%s isn't one of the config's states", state)
+	}
+}
+
+func (v *validation) duration(field, duration string) {
+	if duration == "" {
+		return
+	}
+
+	if _, err := time.ParseDuration(duration); err != nil {
+		v.add(field, "%s", err)
+	}
+}
+
+// reservedPorts are the ports configs can't have as their own
+func (g *gnocker) reservedPorts() map[int]string {
+	reserved := map[int]string{g.port: "main"}
+	if g.admin != g.app {
+		reserved[g.configPort] = "config"
+	}
+
+	return reserved
+}
+
+// join appends a field to the field path it is nested in
+func join(parent, field string) string {
+	if parent == "" {
+		return field
+	}
+
+	return parent + "." + field
+}
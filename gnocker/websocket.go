@@ -0,0 +1,404 @@
+package gnocker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber"
+	"github.com/valyala/fasthttp"
+	"github.com/zerbitx/gnockgnock/spec"
+)
+
+type (
+	// webSocketScript is the compiled form of a spec.WebSocket
+	webSocketScript struct {
+		onConnect []webSocketMessage
+		replies   []webSocketReply
+		pushes    []webSocketPush
+		close     *webSocketClose
+	}
+
+	webSocketMessage struct {
+		text  responseValue
+		delay time.Duration
+	}
+
+	webSocketReply struct {
+		text  []valueMatcher
+		json  []valueMatcher
+		send  []webSocketMessage
+		close *webSocketClose
+	}
+
+	webSocketPush struct {
+		every time.Duration
+		text  responseValue
+	}
+
+	webSocketClose struct {
+		code   int
+		reason string
+		after  time.Duration
+	}
+
+	// webSocketConn is a WebSocket the script is run over, which the pushes and replies write to concurrently
+	webSocketConn struct {
+		conn *websocket.Conn
+		// mu guards writes, so a close frame is the last thing written
+		mu sync.Mutex
+		// closed is closed once the server has sent its close frame
+		closed chan struct{}
+	}
+)
+
+const (
+	// webSocketMaxMessage is the largest message a client can send, bigger ones close the connection
+	webSocketMaxMessage = 1 << 20
+	// webSocketCloseTimeout is how long the client has to answer the server's close frame
+	webSocketCloseTimeout = time.Second
+)
+
+var (
+	errWebSocketClosed = errors.New("websocket closed")
+
+	webSocketUpgrader = websocket.FastHTTPUpgrader{
+		// Mocks stand in for services called from anywhere
+		CheckOrigin: func(*fasthttp.RequestCtx) bool { return true },
+	}
+)
+
+// webSocketHandler upgrades requests to a WebSocket and runs the script over it
+func (g *gnocker) webSocketHandler(config *compiledConfig, options spec.Response) (func(c *fiber.Ctx), error) {
+	script, err := newWebSocketScript(config.name, options.WebSocket, config.funcs)
+	if err != nil {
+		g.logger.WithError(err).Error("Failed to parse websocket")
+		return nil, err
+	}
+
+	return func(c *fiber.Ctx) {
+		if !websocket.FastHTTPIsWebSocketUpgrade(c.Fasthttp) {
+			c.Set(fiber.HeaderUpgrade, "websocket")
+			c.Set("Sec-WebSocket-Version", "13")
+			c.SendStatus(http.StatusUpgradeRequired)
+			return
+		}
+
+		// The context is reused once the handler returns, the script is run with what it needs of the request
+		data := templateData(c, config.name)
+
+		err := webSocketUpgrader.Upgrade(c.Fasthttp, func(conn *websocket.Conn) {
+			conn.SetReadLimit(webSocketMaxMessage)
+
+			script.run(newWebSocketConn(conn), data, g.done, func(err error) {
+				g.logger.WithError(err).Error("failed to render websocket message")
+			})
+		})
+		if err != nil {
+			g.logger.WithError(err).Error("failed to upgrade to a websocket")
+		}
+	}, nil
+}
+
+func newWebSocketScript(configName string, ws *spec.WebSocket, funcs map[string]interface{}) (*webSocketScript, error) {
+	script := &webSocketScript{}
+	var err error
+
+	if script.onConnect, err = newWebSocketMessages(configName, ws.OnConnect, funcs); err != nil {
+		return nil, fmt.Errorf("onConnect%w", err)
+	}
+
+	for i, reply := range ws.Replies {
+		compiled := webSocketReply{}
+
+		if reply.Text != nil {
+			if compiled.text, err = newValueMatchers("text", map[string]spec.ValueMatch{"text": *reply.Text}); err != nil {
+				return nil, fmt.Errorf("replies[%d].text: %w", i, err)
+			}
+		}
+
+		if compiled.json, err = newValueMatchers("json", reply.JSON); err != nil {
+			return nil, fmt.Errorf("replies[%d].json: %w", i, err)
+		}
+
+		if compiled.send, err = newWebSocketMessages(configName, reply.Send, funcs); err != nil {
+			return nil, fmt.Errorf("replies[%d].send%w", i, err)
+		}
+
+		if compiled.close, err = newWebSocketClose(reply.Close); err != nil {
+			return nil, fmt.Errorf("replies[%d].close: %w", i, err)
+		}
+
+		script.replies = append(script.replies, compiled)
+	}
+
+	for i, push := range ws.Pushes {
+		every, err := time.ParseDuration(push.Every)
+		if err != nil || every <= 0 {
+			return nil, fmt.Errorf("pushes[%d].every: %q is not a positive duration", i, push.Every)
+		}
+
+		text, err := newResponseValue(configName, "text", push.Text, funcs)
+		if err != nil {
+			return nil, fmt.Errorf("pushes[%d].text: %w", i, err)
+		}
+
+		script.pushes = append(script.pushes, webSocketPush{every: every, text: text})
+	}
+
+	if script.close, err = newWebSocketClose(ws.Close); err != nil {
+		return nil, fmt.Errorf("close: %w", err)
+	}
+
+	return script, nil
+}
+
+func newWebSocketMessages(configName string, messages []spec.WebSocketMessage, funcs map[string]interface{}) ([]webSocketMessage, error) {
+	compiled := make([]webSocketMessage, 0, len(messages))
+
+	for i, message := range messages {
+		m := webSocketMessage{}
+
+		if message.Delay != "" {
+			delay, err := time.ParseDuration(message.Delay)
+			if err != nil {
+				return nil, fmt.Errorf("[%d].delay: %w", i, err)
+			}
+
+			m.delay = delay
+		}
+
+		text, err := newResponseValue(configName, "text", message.Text, funcs)
+		if err != nil {
+			return nil, fmt.Errorf("[%d].text: %w", i, err)
+		}
+
+		m.text = text
+		compiled = append(compiled, m)
+	}
+
+	return compiled, nil
+}
+
+func newWebSocketClose(c *spec.WebSocketClose) (*webSocketClose, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	compiled := &webSocketClose{code: c.Code, reason: c.Reason}
+	if compiled.code == 0 {
+		compiled.code = websocket.CloseNormalClosure
+	}
+
+	// 1004 to 1006 and 1015 are reserved for clients to report what happened, they're never sent
+	switch {
+	case compiled.code < 1000 || compiled.code > 4999,
+		compiled.code >= 1004 && compiled.code <= 1006,
+		compiled.code == 1015:
+		return nil, fmt.Errorf("%d is not a close code that can be sent", compiled.code)
+	case len(compiled.reason) > 123:
+		return nil, errors.New("the reason can be at most 123 bytes")
+	}
+
+	if c.After != "" {
+		after, err := time.ParseDuration(c.After)
+		if err != nil {
+			return nil, fmt.Errorf("after: %w", err)
+		}
+
+		compiled.after = after
+	}
+
+	return compiled, nil
+}
+
+// run holds the conversation until either side closes the connection, or done is closed
+func (s *webSocketScript) run(ws *webSocketConn, data map[string]interface{}, done <-chan struct{}, failed func(error)) {
+	finished := make(chan struct{})
+	defer close(finished)
+
+	go func() {
+		select {
+		case <-done:
+			ws.close(websocket.CloseGoingAway, "")
+		case <-finished:
+		}
+	}()
+
+	if s.close != nil {
+		timer := time.AfterFunc(s.close.after, func() {
+			ws.close(s.close.code, s.close.reason)
+		})
+		defer timer.Stop()
+	}
+
+	if !ws.sendAll(s.onConnect, data, failed) {
+		return
+	}
+
+	for _, push := range s.pushes {
+		go func(push webSocketPush) {
+			ticker := time.NewTicker(push.every)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					if !ws.sendValue(push.text, data, failed) {
+						return
+					}
+				case <-finished:
+					return
+				}
+			}
+		}(push)
+	}
+
+	for {
+		message, err := ws.read()
+		if err != nil {
+			return
+		}
+
+		for _, reply := range s.replies {
+			replyData, ok := reply.matches(message, data)
+			if !ok {
+				continue
+			}
+
+			if ws.sendAll(reply.send, replyData, failed) && reply.close != nil && ws.wait(reply.close.after) {
+				ws.close(reply.close.code, reply.close.reason)
+			}
+
+			break
+		}
+	}
+}
+
+// matches reports whether the reply is for the message, giving the data its messages are rendered with if so
+func (r webSocketReply) matches(message []byte, data map[string]interface{}) (map[string]interface{}, bool) {
+	var document interface{}
+	decoder := json.NewDecoder(bytes.NewReader(message))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		document = nil
+	}
+
+	for _, vm := range r.text {
+		if !vm.matches(string(message), true) {
+			return nil, false
+		}
+	}
+
+	for _, vm := range r.json {
+		var value string
+		var found bool
+		if document != nil {
+			value, found = jsonPath(document, vm.key)
+		}
+
+		if !vm.matches(value, found) {
+			return nil, false
+		}
+	}
+
+	replyData := make(map[string]interface{}, len(data)+2)
+	for key, value := range data {
+		replyData[key] = value
+	}
+	replyData["Message"] = string(message)
+	replyData["MessageJSON"] = document
+
+	return replyData, true
+}
+
+func newWebSocketConn(conn *websocket.Conn) *webSocketConn {
+	return &webSocketConn{conn: conn, closed: make(chan struct{})}
+}
+
+// sendAll sends the messages in order, waiting each one's delay, reporting false if the connection closed meanwhile
+func (ws *webSocketConn) sendAll(messages []webSocketMessage, data interface{}, failed func(error)) bool {
+	for _, message := range messages {
+		if !ws.wait(message.delay) || !ws.sendValue(message.text, data, failed) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sendValue renders and sends a text message, one that fails to render is skipped
+func (ws *webSocketConn) sendValue(text responseValue, data interface{}, failed func(error)) bool {
+	rendered, err := text.render(data)
+	if err != nil {
+		failed(err)
+		return true
+	}
+
+	return ws.write([]byte(rendered)) == nil
+}
+
+// wait waits for d, reporting false if the connection closed meanwhile
+func (ws *webSocketConn) wait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ws.closed:
+		return false
+	}
+}
+
+func (ws *webSocketConn) write(text []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	select {
+	case <-ws.closed:
+		return errWebSocketClosed
+	default:
+	}
+
+	return ws.conn.WriteMessage(websocket.TextMessage, text)
+}
+
+// close sends a close frame, once, then gives the client a moment to answer with its own before reads give up
+func (ws *webSocketConn) close(code int, reason string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	select {
+	case <-ws.closed:
+		return
+	default:
+	}
+
+	deadline := time.Now().Add(webSocketCloseTimeout)
+	_ = ws.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+	_ = ws.conn.SetReadDeadline(deadline)
+	close(ws.closed)
+}
+
+// read returns the next message, the connection answers pings and close frames along the way.  Text messages have to
+// be UTF-8, which is for the application to check.
+func (ws *webSocketConn) read() ([]byte, error) {
+	messageType, message, err := ws.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	if messageType == websocket.TextMessage && !utf8.Valid(message) {
+		ws.close(websocket.CloseInvalidFramePayloadData, "text messages must be UTF-8")
+		return nil, errWebSocketClosed
+	}
+
+	return message, nil
+}
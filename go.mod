@@ -3,6 +3,7 @@ module github.com/zerbitx/gnockgnock
 go 1.14
 
 require (
+	github.com/fasthttp/websocket v1.4.2
 	github.com/gofiber/fiber v1.12.5-0.20200705093422-d2577a964350
 	github.com/kelseyhightower/envconfig v1.4.0
 	github.com/kr/pretty v0.1.0 // indirect
@@ -10,6 +11,7 @@ require (
 	github.com/onsi/gomega v1.10.1
 	github.com/sirupsen/logrus v1.6.0
 	github.com/stretchr/testify v1.3.0 // indirect
+	github.com/valyala/fasthttp v1.14.0
 	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 	gopkg.in/yaml.v2 v2.3.0
 )
@@ -5,12 +5,13 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"syscall"
 
 	"github.com/sirupsen/logrus"
+	"github.com/zerbitx/gnockgnock/certs"
 	"github.com/zerbitx/gnockgnock/config"
+	"github.com/zerbitx/gnockgnock/configfile"
 	"github.com/zerbitx/gnockgnock/gnocker"
-	"github.com/zerbitx/gnockgnock/spec"
-	"gopkg.in/yaml.v2"
 )
 
 func main() {
@@ -20,31 +21,67 @@ func main() {
 	setLogLevel(cfg.LogLevel)
 	logrus.SetReportCaller(true)
 
+	tlsConfig, authority, err := certs.Setup(certs.Settings{
+		CertFile:     cfg.TLSCertFile,
+		KeyFile:      cfg.TLSKeyFile,
+		Generate:     cfg.TLSGenerate,
+		Hosts:        append(cfg.TLSHosts, cfg.Host),
+		ClientCAFile: cfg.TLSClientCAFile,
+		ClientAuth:   cfg.TLSClientAuth,
+	})
+	if err != nil {
+		log.Fatalf("failed to setup TLS: %s", err)
+	}
+
 	g := gnocker.New(
 		gnocker.WithHost(cfg.Host),
 		gnocker.WithPort(cfg.Port),
+		gnocker.WithConfigPort(cfg.ConfigPort),
+		gnocker.WithSinglePort(cfg.SinglePort),
 		gnocker.WithConfigBasePath(cfg.ConfigBasePath),
+		gnocker.WithJournalSize(cfg.JournalSize),
+		gnocker.WithProxy(cfg.ProxyURL),
+		gnocker.WithStrictDecoding(cfg.StrictConfig),
+		gnocker.WithTLS(tlsConfig),
+		gnocker.WithAuthority(authority),
 		gnocker.WithLogger(logger))
 
 	go captureInterrupt(g.Shutdown)
 
-	// Try to load a default config
-	{
-		configYaml, err := os.OpenFile(cfg.ConfigFilePath, os.O_RDONLY, 0644)
+	// Load the default configs, no configs...no problem
+	reload := func() error {
+		operations, err := configfile.Load(cfg.ConfigFilePath, cfg.StrictConfig)
+		if err != nil {
+			return err
+		}
 
-		// No config...no problem
-		if err == nil {
-			operations := spec.Configurations{}
-			if err := yaml.NewDecoder(configYaml).Decode(operations); err != nil {
-				log.Fatalf("Failed to decode yaml: %operations", err)
-			}
+		added, removed, err := g.SyncFileConfigs(operations)
+		if err != nil {
+			return err
+		}
 
-			if err := g.AddConfig(operations); err != nil {
-				log.Fatalf("failed to setup initial config: %s", err)
-			}
+		logger.WithFields(logrus.Fields{"added": added, "removed": removed}).Info("loaded config files")
+
+		return nil
+	}
+
+	if err := reload(); err != nil {
+		log.Fatalf("failed to setup initial config: %s", err)
+	}
+
+	// Keep them up to date
+	logReload := func() {
+		if err := reload(); err != nil {
+			logger.WithError(err).Error("failed to reload config files")
 		}
 	}
 
+	go captureHangup(logReload)
+
+	if cfg.ConfigPollInterval > 0 {
+		go configfile.Watch(cfg.ConfigFilePath, cfg.ConfigPollInterval, nil, logReload)
+	}
+
 	fmt.Println("Servers shutdown due to: ", g.Start())
 }
 
@@ -60,7 +97,7 @@ func setLogLevel(lvlStr string) {
 }
 
 func captureInterrupt(shutdown func() error) {
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 
 	<-c
@@ -70,3 +107,12 @@ func captureInterrupt(shutdown func() error) {
 		fmt.Println("Something went wrong during shutdown: ", err)
 	}
 }
+
+func captureHangup(reload func()) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+
+	for range c {
+		reload()
+	}
+}
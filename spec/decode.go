@@ -0,0 +1,157 @@
+package spec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Decode reads a YAML (or JSON) document into v.  Strict decoding rejects keys that don't belong to any field, with the
+// line each was found on.
+func Decode(r io.Reader, v interface{}, strict bool) error {
+	decoder := yaml.NewDecoder(r)
+	decoder.SetStrict(strict)
+
+	return decoder.Decode(v)
+}
+
+type (
+	// The types below decode their namesakes without recursing into UnmarshalYAML
+
+	plainResponse   Response
+	plainResource   Resource
+	plainValueMatch ValueMatch
+
+	// responseAliases are the alternate names Response accepts for some of its fields
+	responseAliases struct {
+		Headers []map[string]string `json:"headers" yaml:"headers"`
+		Status  int                 `json:"status" yaml:"status"`
+	}
+
+	// aliasedResponse is a Response along with its aliases
+	aliasedResponse struct {
+		Response plainResponse   `yaml:",inline"`
+		Aliases  responseAliases `yaml:",inline"`
+	}
+)
+
+// UnmarshalYAML decodes a Response, accepting headers for responseHeaders and status for statusCode
+func (r *Response) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	aliased := aliasedResponse{}
+
+	if err := unmarshal(&aliased); err != nil {
+		return renameTypes(err, "spec.Response", aliased, aliased.Response)
+	}
+
+	*r = Response(aliased.Response)
+
+	return r.applyAliases(aliased.Aliases)
+}
+
+// UnmarshalJSON decodes a Response, accepting headers for responseHeaders and status for statusCode
+func (r *Response) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*plainResponse)(r)); err != nil {
+		return err
+	}
+
+	aliases := responseAliases{}
+	if err := json.Unmarshal(data, &aliases); err != nil {
+		return err
+	}
+
+	return r.applyAliases(aliases)
+}
+
+func (r *Response) applyAliases(aliases responseAliases) error {
+	if aliases.Headers != nil {
+		if r.Headers != nil {
+			return errors.New("only one of headers and responseHeaders can be set")
+		}
+		r.Headers = aliases.Headers
+	}
+
+	if aliases.Status != 0 {
+		if r.StatusCode != 0 {
+			return errors.New("only one of status and statusCode can be set")
+		}
+		r.StatusCode = aliases.Status
+	}
+
+	return nil
+}
+
+// UnmarshalYAML decodes a Resource, with its data's nested objects keyed by strings as JSON would have them
+func (r *Resource) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal((*plainResource)(r)); err != nil {
+		return renameTypes(err, "spec.Resource", plainResource{})
+	}
+
+	for i, item := range r.Data {
+		for key, value := range item {
+			normalized, err := stringKeys(value)
+			if err != nil {
+				return fmt.Errorf("data[%d].%s: %w", i, key, err)
+			}
+			r.Data[i][key] = normalized
+		}
+	}
+
+	return nil
+}
+
+// renameTypes has the errors yaml reports name the type being decoded, rather than the types it's decoded through
+func renameTypes(err error, name string, through ...interface{}) error {
+	typeErr, ok := err.(*yaml.TypeError)
+	if !ok {
+		return err
+	}
+
+	renamed := &yaml.TypeError{Errors: make([]string, len(typeErr.Errors))}
+	for i, message := range typeErr.Errors {
+		for _, t := range through {
+			message = strings.ReplaceAll(message, reflect.TypeOf(t).String(), name)
+		}
+		renamed.Errors[i] = message
+	}
+
+	return renamed
+}
+
+// stringKeys converts the maps yaml.v2 decodes objects into to ones keyed by strings
+func stringKeys(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			k, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("key %v is not a string", key)
+			}
+
+			normalized, err := stringKeys(nested)
+			if err != nil {
+				return nil, err
+			}
+			converted[k] = normalized
+		}
+
+		return converted, nil
+	case []interface{}:
+		for i, nested := range v {
+			normalized, err := stringKeys(nested)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = normalized
+		}
+
+		return v, nil
+	default:
+		return value, nil
+	}
+}
@@ -1,6 +1,9 @@
 package spec
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type (
 	// Configurations is a mapping from name to a set of path/method expectations
@@ -11,6 +14,28 @@ type (
 	Configuration struct {
 		TTL   string               `json:"ttl" yaml:"ttl"`
 		Paths map[string]Responses `json:"paths" yaml:"paths"`
+		// Proxy is an upstream base URL requests this config has no response for are forwarded to and recorded from
+		Proxy string `json:"proxy" yaml:"proxy"`
+		// Port serves the config on a port of its own instead of the main one, so it can stand in for a service of its own
+		Port int `json:"port" yaml:"port"`
+		// Host is the interface the config's own port listens on, the server's host when empty
+		Host string `json:"host" yaml:"host"`
+		// Seed makes the random values templates generate the same every time the config is added, 0 is random
+		Seed int64 `json:"seed" yaml:"seed"`
+		// States are the states the config's scenario can be in, the first being where it starts
+		States []string `json:"states" yaml:"states"`
+		// Resources are in memory REST collections served by the config, keyed by name
+		Resources map[string]Resource `json:"resources" yaml:"resources"`
+	}
+
+	// Resource is a collection served with list, get, create, replace, patch and delete routes
+	Resource struct {
+		// Path is where the collection is served, / and the resource's name by default
+		Path string `json:"path,omitempty" yaml:"path,omitempty"`
+		// IDField is the field items are identified by, id by default
+		IDField string `json:"idField,omitempty" yaml:"idField,omitempty"`
+		// Data are the items the collection starts out with
+		Data []map[string]interface{} `json:"data,omitempty" yaml:"data,omitempty"`
 	}
 
 	// Responses map each method's response for a given path
@@ -22,7 +47,234 @@ type (
 		BodyTemplate  string              `json:"bodyTemplate" yaml:"bodyTemplate"`
 		StatusCode    int                 `json:"statusCode" yaml:"statusCode"`
 		Headers       []map[string]string `json:"responseHeaders" yaml:"responseHeaders"`
+		Cookies       []Cookie            `json:"cookies" yaml:"cookies"`
 		Delay         string              `json:"delay" yaml:"delay"`
 		DelayDuration time.Duration       `json:"-" yaml:"-"`
+		// Latency draws the delay of each response from a distribution, instead of a fixed Delay
+		Latency *Latency `json:"latency" yaml:"latency"`
+		// FailureRate is the fraction of requests, from 0 to 1, that get the Failure response instead
+		FailureRate float64 `json:"failureRate" yaml:"failureRate"`
+		// Failure is served at the FailureRate, a 500 if it isn't set
+		Failure *Response `json:"failure" yaml:"failure"`
+		// Throttle sends the body a chunk at a time instead of all at once
+		Throttle *Throttle `json:"throttle" yaml:"throttle"`
+
+		// ETag is the response's entity tag, or auto to compute one from the body.  Along with LastModified it has
+		// conditional requests answered with a 304 or 412 as they should be.
+		ETag string `json:"etag" yaml:"etag"`
+		// LastModified is an RFC 3339 or HTTP date
+		LastModified string `json:"lastModified" yaml:"lastModified"`
+
+		// Fault breaks the response the way a failing upstream would, after any delay: reset, close, headersThenHang,
+		// truncatedBody, malformed or hang
+		Fault string `json:"fault" yaml:"fault"`
+
+		// TemplateEngine is text or html, by default html when the Content-Type header is HTML and text otherwise
+		TemplateEngine string `json:"templateEngine" yaml:"templateEngine"`
+		// StatusCodeTemplate is rendered with the request to give the status code, overriding StatusCode.  Header values
+		// are templates too when they contain {{.
+		StatusCodeTemplate string `json:"statusCodeTemplate" yaml:"statusCodeTemplate"`
+
+		// Events make the response a stream of server-sent events instead of a body, the connection is kept open until
+		// the last of them has been sent
+		Events []Event `json:"events" yaml:"events"`
+		// LoopEvents starts over from the first event after the last, until the client disconnects
+		LoopEvents bool `json:"loopEvents" yaml:"loopEvents"`
+
+		// WebSocket has the path accept upgrades to a WebSocket and hold a scripted conversation over it, requests that
+		// aren't upgrades get a 426
+		WebSocket *WebSocket `json:"webSocket" yaml:"webSocket"`
+
+		// Sequence replaces this response with an ordered list of responses, one per successive call
+		Sequence []Response `json:"sequence" yaml:"sequence"`
+		// SequenceMode decides what to serve once the sequence is exhausted (last, loop or notFound)
+		SequenceMode string `json:"sequenceMode" yaml:"sequenceMode"`
+
+		// Match restricts this response to requests that look a certain way
+		Match *Match `json:"match" yaml:"match"`
+		// State makes the response only eligible while the config's scenario is in that state
+		State string `json:"state" yaml:"state"`
+		// NextState is the state the config's scenario moves to once the response is served
+		NextState string `json:"nextState" yaml:"nextState"`
+
+		// Candidates are evaluated in order, the first one matching the request is served, otherwise this response is
+		// served
+		Candidates []Response `json:"candidates" yaml:"candidates"`
+	}
+
+	// Latency is a distribution response delays are drawn from, using the config's seed
+	Latency struct {
+		// Distribution is uniform (between Min and Max), normal or logNormal (with Mean and StdDev) or percentiles
+		Distribution string `json:"distribution" yaml:"distribution"`
+		Min          string `json:"min,omitempty" yaml:"min,omitempty"`
+		Max          string `json:"max,omitempty" yaml:"max,omitempty"`
+		Mean         string `json:"mean,omitempty" yaml:"mean,omitempty"`
+		StdDev       string `json:"stdDev,omitempty" yaml:"stdDev,omitempty"`
+		// Percentiles maps percentiles, e.g. 50 or 99.9, to the latency at them.  Latencies in between are interpolated.
+		Percentiles map[string]string `json:"percentiles,omitempty" yaml:"percentiles,omitempty"`
+	}
+
+	// Throttle streams a body in chunks, either at a rate or with a delay between them
+	Throttle struct {
+		// BytesPerSecond spaces the chunks out so the body is sent at that rate
+		BytesPerSecond int `json:"bytesPerSecond,omitempty" yaml:"bytesPerSecond,omitempty"`
+		// ChunkDelay is the wait between chunks, instead of a rate
+		ChunkDelay string `json:"chunkDelay,omitempty" yaml:"chunkDelay,omitempty"`
+		// ChunkSize is the number of bytes sent at a time, by default a tenth of BytesPerSecond or 1024
+		ChunkSize int `json:"chunkSize,omitempty" yaml:"chunkSize,omitempty"`
+	}
+
+	// Event is a server-sent event.  Its Event, Data and ID are templates when they contain {{.
+	Event struct {
+		// Event is the event's type, which clients take to be message when it's empty
+		Event string `json:"event,omitempty" yaml:"event,omitempty"`
+		// Data is sent as a data line for each of its lines
+		Data string `json:"data" yaml:"data"`
+		ID   string `json:"id,omitempty" yaml:"id,omitempty"`
+		// Retry is how many milliseconds the client should wait before reconnecting
+		Retry int `json:"retry,omitempty" yaml:"retry,omitempty"`
+		// Delay is the wait before the event is sent
+		Delay string `json:"delay,omitempty" yaml:"delay,omitempty"`
+	}
+
+	// WebSocket is the script of a WebSocket conversation
+	WebSocket struct {
+		// OnConnect are sent, in order, once the connection is upgraded
+		OnConnect []WebSocketMessage `json:"onConnect" yaml:"onConnect"`
+		// Replies answer the messages the client sends, with the first reply the message matches
+		Replies []WebSocketReply `json:"replies" yaml:"replies"`
+		// Pushes are sent periodically for as long as the connection is open
+		Pushes []WebSocketPush `json:"pushes" yaml:"pushes"`
+		// Close has the server close the connection a while after it's upgraded
+		Close *WebSocketClose `json:"close" yaml:"close"`
+	}
+
+	// WebSocketMessage is a text message.  Text is a template when it contains {{, executed with the request that was
+	// upgraded, and for replies the message replied to as .Message and, if it's JSON, .MessageJSON.
+	WebSocketMessage struct {
+		Text string `json:"text" yaml:"text"`
+		// Delay is the wait before the message is sent
+		Delay string `json:"delay,omitempty" yaml:"delay,omitempty"`
+	}
+
+	// WebSocketReply answers client messages that are, or match, Text and whose JSON fields (dot separated paths, as
+	// with request matching) match JSON.  A reply with neither answers every message.
+	WebSocketReply struct {
+		Text *ValueMatch           `json:"text,omitempty" yaml:"text,omitempty"`
+		JSON map[string]ValueMatch `json:"json,omitempty" yaml:"json,omitempty"`
+		Send []WebSocketMessage    `json:"send" yaml:"send"`
+		// Close closes the connection once the reply is sent
+		Close *WebSocketClose `json:"close,omitempty" yaml:"close,omitempty"`
+	}
+
+	// WebSocketPush is a message sent every so often
+	WebSocketPush struct {
+		Every string `json:"every" yaml:"every"`
+		Text  string `json:"text" yaml:"text"`
+	}
+
+	// WebSocketClose closes a WebSocket with a close code, 1000 (normal closure) by default
+	WebSocketClose struct {
+		Code   int    `json:"code,omitempty" yaml:"code,omitempty"`
+		Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+		// After is the wait before closing
+		After string `json:"after,omitempty" yaml:"after,omitempty"`
+	}
+
+	// Cookie is a cookie to set on the response
+	Cookie struct {
+		Name   string `json:"name" yaml:"name"`
+		Value  string `json:"value" yaml:"value"`
+		Path   string `json:"path,omitempty" yaml:"path,omitempty"`
+		Domain string `json:"domain,omitempty" yaml:"domain,omitempty"`
+		// Expires is either a duration from when the cookie is set, e.g. 24h, or an RFC 3339 time
+		Expires  string `json:"expires,omitempty" yaml:"expires,omitempty"`
+		HTTPOnly bool   `json:"httpOnly,omitempty" yaml:"httpOnly,omitempty"`
+		Secure   bool   `json:"secure,omitempty" yaml:"secure,omitempty"`
+		// SameSite is lax (the default), strict or none
+		SameSite string `json:"sameSite,omitempty" yaml:"sameSite,omitempty"`
+	}
+
+	// Match holds the expectations a request has to meet, keyed by header name, query/form parameter, cookie name or
+	// JSON body path (dot separated, e.g. user.roles.0)
+	Match struct {
+		Headers map[string]ValueMatch `json:"headers" yaml:"headers"`
+		Query   map[string]ValueMatch `json:"query" yaml:"query"`
+		JSON    map[string]ValueMatch `json:"json" yaml:"json"`
+		Form    map[string]ValueMatch `json:"form" yaml:"form"`
+		Cookies map[string]ValueMatch `json:"cookies" yaml:"cookies"`
+	}
+
+	// ValueMatch is an expectation on a single request value.  A plain string is shorthand for Equals and an empty
+	// ValueMatch only requires the value to be present.
+	ValueMatch struct {
+		Equals  string `json:"equals,omitempty" yaml:"equals,omitempty"`
+		Regex   string `json:"regex,omitempty" yaml:"regex,omitempty"`
+		Present *bool  `json:"present,omitempty" yaml:"present,omitempty"`
 	}
 )
+
+const (
+	// SequenceModeLast keeps serving the last response of an exhausted sequence (the default)
+	SequenceModeLast = "last"
+	// SequenceModeLoop starts an exhausted sequence over from the first response
+	SequenceModeLoop = "loop"
+	// SequenceModeNotFound responds with a 404 once the sequence is exhausted
+	SequenceModeNotFound = "notFound"
+
+	// ETagAuto has the ETag computed from the body served
+	ETagAuto = "auto"
+
+	// LatencyUniform spreads latencies evenly between Min and Max
+	LatencyUniform = "uniform"
+	// LatencyNormal draws latencies from a normal distribution with Mean and StdDev, never below 0
+	LatencyNormal = "normal"
+	// LatencyLogNormal draws latencies from a log-normal distribution with Mean and StdDev, a long tail of slow ones
+	LatencyLogNormal = "logNormal"
+	// LatencyPercentiles draws latencies following a table of percentiles
+	LatencyPercentiles = "percentiles"
+
+	// FaultReset resets the connection instead of responding
+	FaultReset = "reset"
+	// FaultClose closes the connection without responding
+	FaultClose = "close"
+	// FaultHeadersThenHang sends the status and headers, then nothing more until the client gives up
+	FaultHeadersThenHang = "headersThenHang"
+	// FaultTruncatedBody sends part of the body with a Content-Length for all of it, then closes the connection
+	FaultTruncatedBody = "truncatedBody"
+	// FaultMalformed responds with something that isn't HTTP
+	FaultMalformed = "malformed"
+	// FaultHang doesn't respond at all until the client gives up
+	FaultHang = "hang"
+
+	// TemplateEngineText executes templates with text/template, leaving what they output as it is
+	TemplateEngineText = "text"
+	// TemplateEngineHTML executes templates with html/template, escaping what they output for HTML
+	TemplateEngineHTML = "html"
+)
+
+// UnmarshalYAML accepts either a plain string to compare against or a full ValueMatch
+func (v *ValueMatch) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var equals string
+	if err := unmarshal(&equals); err == nil {
+		v.Equals = equals
+		return nil
+	}
+
+	if err := unmarshal((*plainValueMatch)(v)); err != nil {
+		return renameTypes(err, "spec.ValueMatch", plainValueMatch{})
+	}
+
+	return nil
+}
+
+// UnmarshalJSON accepts either a plain string to compare against or a full ValueMatch
+func (v *ValueMatch) UnmarshalJSON(data []byte) error {
+	var equals string
+	if err := json.Unmarshal(data, &equals); err == nil {
+		v.Equals = equals
+		return nil
+	}
+
+	return json.Unmarshal(data, (*plainValueMatch)(v))
+}